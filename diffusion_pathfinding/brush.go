@@ -0,0 +1,133 @@
+package main
+
+type BrushMode int
+
+const N_BRUSHES = 4
+
+const (
+	BRUSH_POINT = 0
+	BRUSH_RECT  = iota
+	BRUSH_LINE  = iota
+	BRUSH_FILL  = iota
+)
+
+var BRUSHNAMES []string = []string{
+	"BRUSH_POINT",
+	"BRUSH_RECT",
+	"BRUSH_LINE",
+	"BRUSH_FILL",
+}
+
+// the cells a brush stroke from a to b covers. BRUSH_POINT and BRUSH_FILL
+// only use a, and the fill covers the region of cells connected to a
+// which are in the same state as it (all free or all obstacles)
+func (w *World) BrushCells(brush BrushMode, a Position, b Position) []Position {
+	switch brush {
+	case BRUSH_RECT:
+		return rectCells(a, b)
+	case BRUSH_LINE:
+		return lineCells(a, b)
+	case BRUSH_FILL:
+		return w.floodCells(a)
+	default:
+		return []Position{a}
+	}
+}
+
+// add (or if !add, remove) obstacles over a brush stroke from a to b.
+// Cells off the grid, and cells holding the entity or a chaser, are left
+// alone
+func (w *World) Paint(brush BrushMode, a Position, b Position, add bool) {
+	occupied := make(map[Position]bool)
+	if w.e != nil {
+		occupied[w.dm.ToGridSpace(w.e.pos)] = true
+	}
+	for _, c := range w.chasers {
+		occupied[w.dm.ToGridSpace(c.pos)] = true
+	}
+	for _, p := range w.BrushCells(brush, a, b) {
+		if !w.dm.InGrid(p.X, p.Y) {
+			continue
+		}
+		if add && !occupied[p] {
+			w.AddObstacle(p)
+		} else if !add {
+			w.RemoveObstacle(p)
+		}
+	}
+}
+
+// every cell in the rectangle with corners a and b
+func rectCells(a Position, b Position) []Position {
+	x0, x1 := a.X, b.X
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	y0, y1 := a.Y, b.Y
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	cells := make([]Position, 0, (x1-x0+1)*(y1-y0+1))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			cells = append(cells, Position{X: x, Y: y})
+		}
+	}
+	return cells
+}
+
+// the cells on the line from a to b (Bresenham)
+func lineCells(a Position, b Position) []Position {
+	dx := b.X - a.X
+	sx := 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	dy := b.Y - a.Y
+	sy := 1
+	if dy < 0 {
+		dy, sy = -dy, -1
+	}
+	var cells []Position
+	x, y := a.X, a.Y
+	err := dx - dy
+	for {
+		cells = append(cells, Position{X: x, Y: y})
+		if x == b.X && y == b.Y {
+			return cells
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x += sx
+		}
+		if e2 < dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// the 4-connected region of cells in the same state (free or obstacle)
+// as p
+func (w *World) floodCells(p Position) []Position {
+	if !w.dm.InGrid(p.X, p.Y) {
+		return nil
+	}
+	state := w.obstacles.Has(p)
+	seen := map[Position]bool{p: true}
+	cells := []Position{p}
+	for i := 0; i < len(cells); i++ {
+		c := cells[i]
+		for _, d := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			n := Position{X: c.X + d[0], Y: c.Y + d[1]}
+			if seen[n] || !w.dm.InGrid(n.X, n.Y) ||
+				w.obstacles.Has(n) != state {
+				continue
+			}
+			seen[n] = true
+			cells = append(cells, n)
+		}
+	}
+	return cells
+}
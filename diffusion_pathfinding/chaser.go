@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 	"math"
 )
 
@@ -11,23 +12,46 @@ type Chaser struct {
 	vel        Vec2D
 	steer      Vec2D
 	moveTarget *Vec2D
+
+	// how strongly each scent layer attracts (or, if negative, repels)
+	weights ScentWeights
+	// how the seek toward the scent is combined with the other steering
+	// forces
+	steering SteeringWeights
+	// index in the world's chasers as of the last time the world hashed
+	// them (to split apart chasers sitting on top of each other)
+	ix int
 }
 
 func NewChaser(pos Vec2D, w *World) *Chaser {
 	return &Chaser{
-		w:   w,
-		pos: pos,
+		w:        w,
+		pos:      pos,
+		weights:  DEFAULT_SCENT_WEIGHTS,
+		steering: w.steering,
+	}
+}
+
+// whether the chaser is sitting on a source it's attracted to
+func (c *Chaser) AtSource(cpos Position) bool {
+	for _, l := range c.w.dm.Layers() {
+		if c.weights[l.Name] > 0 && l.IsSource(cpos.X, cpos.Y) {
+			return true
+		}
 	}
+	return false
 }
 
+// the neighboring cell (or the current one) where the weighted
+// combination of scent layers is highest
 func (c *Chaser) GreaterNeighbor() *Vec2D {
 	cpos := c.w.dm.ToGridSpace(c.pos)
-	max := 0.0
-	next := cpos
-	if c.w.dm.d[cpos.X][cpos.Y] == 1.0 {
+	if c.AtSource(cpos) {
 		return nil
 	}
-	for _, ix := range neighborIXs {
+	max := c.w.dm.Combined(c.weights, cpos.X, cpos.Y)
+	next := cpos
+	for _, ix := range gridpath.NeighborIXs {
 		x, y := cpos.X+ix[0], cpos.Y+ix[1]
 		if !c.w.dm.InGrid(x, y) {
 			continue
@@ -37,10 +61,10 @@ func (c *Chaser) GreaterNeighbor() *Vec2D {
 			c.w.dm.CellHasObstacle(cpos.X, cpos.Y+ix[1])) {
 			continue
 		}
-		d := c.w.dm.d[x][y]
+		d := c.w.dm.Combined(c.weights, x, y)
 		if d > max {
 			max = d
-			next = Position{x, y}
+			next = Position{X: x, Y: y}
 		}
 	}
 	p := c.w.dm.ToWorldSpace(next)
@@ -57,63 +81,39 @@ func (c *Chaser) UpdateVel() {
 		c.moveTarget = c.GreaterNeighbor()
 	}
 	if c.moveTarget != nil &&
-		c.pos.Sub(*c.moveTarget).Magnitude() < GRIDCELL_WORLD_W/4 {
+		c.pos.Sub(*c.moveTarget).Magnitude() < c.w.dm.cellSize/4 {
 		c.moveTarget = c.GreaterNeighbor()
 	}
-	if c.moveTarget == nil {
+	var seek Vec2D
+	if c.moveTarget != nil {
+		// if we're already there (e.g. the field hasn't reached us yet, so
+		// the best cell is our own) there's nothing to seek, and Unit() of
+		// a zero vector is NaN
+		if toward := c.moveTarget.Sub(c.pos); toward.Magnitude() != 0 {
+			seek = toward.Unit()
+		}
+	}
+	steer := seek.Scale(c.steering.Seek)
+	if c.steering.Separation != 0 || c.steering.Alignment != 0 {
+		c.w.nearBuf = c.w.chaserHash.Near(
+			c.pos, ALIGNMENT_RADIUS, c.w.nearBuf[:0])
+		steer = steer.Add(
+			c.separation(c.w.nearBuf).Scale(c.steering.Separation))
+		steer = steer.Add(
+			c.alignment(c.w.nearBuf).Scale(c.steering.Alignment))
+	}
+	if c.steering.Avoidance != 0 {
+		steer = steer.Add(c.avoidance().Scale(c.steering.Avoidance))
+	}
+	if steer.Magnitude() == 0 {
 		return
 	}
-	toward := c.moveTarget.Sub(c.pos)
-	c.steer = toward.Unit()
+	c.steer = steer.Truncate(1)
 	angle := c.vel.AngleBetween(c.steer)
 	maxVel := (MOVESPEED / 2) * (1 - 0.9*(angle/math.Pi))
 	c.vel = c.vel.Add(c.steer).Truncate(maxVel)
 }
 
 func (c *Chaser) Move() {
-	vel := c.vel
-	vX := c.vel.XComponent()
-	vY := c.vel.YComponent()
-	for _, o := range c.w.obstacles {
-		orec := Rect2D{
-			float64(GRIDCELL_WORLD_W * o.X),
-			float64(GRIDCELL_WORLD_H * o.Y),
-			GRIDCELL_WORLD_W,
-			GRIDCELL_WORLD_H}
-		erec := Rect2D{
-			c.pos.X - POINTSZ/2 - 2, c.pos.Y - POINTSZ/2 - 2,
-			POINTSZ + 4, POINTSZ + 4}
-		erecX := erec.Add(vX)
-		if orec.Overlaps(erecX) {
-			dxL := orec.X - (c.pos.X + POINTSZ/2)
-			dxR := c.pos.X - (orec.X + orec.W + POINTSZ/2)
-			if dxL > 0 {
-				// we are to the left
-				vX = vX.Truncate(dxL * 0.2)
-			} else if dxR > 0 {
-				// we are to the right
-				vX = vX.Truncate(dxR * 0.2)
-			}
-		}
-		if orec.Overlaps(erec.Add(vX)) {
-			vX = Vec2D{0, 0}
-		}
-		erecY := erec.Add(vY)
-		if orec.Overlaps(erecY) {
-			dyD := orec.Y - (c.pos.Y + POINTSZ/2)
-			dyU := c.pos.Y - (orec.Y + orec.H + POINTSZ/2)
-			if dyD > 0 {
-				// we are down
-				vY = vY.Truncate(dyD * 0.2)
-			} else if dyU > 0 {
-				// we are up
-				vY = vY.Truncate(dyU * 0.2)
-			}
-		}
-		if orec.Overlaps(erec.Add(vY)) {
-			vY = Vec2D{0, 0}
-		}
-	}
-	vel = vX.Add(vY)
-	c.pos = c.pos.Add(vel)
+	c.pos = c.pos.Add(c.w.Collide(c.pos, c.vel, POINTSZ))
 }
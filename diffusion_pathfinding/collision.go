@@ -0,0 +1,55 @@
+package main
+
+// the velocity an agent of the given size at pos can actually move with
+// when trying to move with vel. The x and y components are checked
+// separately against each obstacle, so agents slide along walls, and each
+// component is cut down as the agent closes on an obstacle. Only the
+// obstacle cells the agent's swept bounding box touches are checked
+func (w *World) Collide(pos Vec2D, vel Vec2D, size float64) Vec2D {
+	vX := vel.XComponent()
+	vY := vel.YComponent()
+	erec := Rect2D{
+		pos.X - size/2 - 2, pos.Y - size/2 - 2,
+		size + 4, size + 4}
+	sweep := erec.Union(erec.Add(vX)).Union(erec.Add(vY))
+	x0, y0, x1, y1 := w.dm.CellRange(sweep)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if !w.dm.CellHasObstacle(x, y) {
+				continue
+			}
+			orec := w.dm.CellRect(Position{X: x, Y: y})
+			erecX := erec.Add(vX)
+			if orec.Overlaps(erecX) {
+				dxL := orec.X - (pos.X + size/2)
+				dxR := pos.X - (orec.X + orec.W + size/2)
+				if dxL > 0 {
+					// we are to the left
+					vX = vX.Truncate(dxL * 0.2)
+				} else if dxR > 0 {
+					// we are to the right
+					vX = vX.Truncate(dxR * 0.2)
+				}
+			}
+			if orec.Overlaps(erec.Add(vX)) {
+				vX = Vec2D{0, 0}
+			}
+			erecY := erec.Add(vY)
+			if orec.Overlaps(erecY) {
+				dyD := orec.Y - (pos.Y + size/2)
+				dyU := pos.Y - (orec.Y + orec.H + size/2)
+				if dyD > 0 {
+					// we are down
+					vY = vY.Truncate(dyD * 0.2)
+				} else if dyU > 0 {
+					// we are up
+					vY = vY.Truncate(dyU * 0.2)
+				}
+			}
+			if orec.Overlaps(erec.Add(vY)) {
+				vY = Vec2D{0, 0}
+			}
+		}
+	}
+	return vX.Add(vY)
+}
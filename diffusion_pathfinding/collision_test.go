@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the collision check as it was before it was indexed by the grid: every
+// obstacle is checked, here in row-major order to match Collide
+func collideAll(w *World, pos Vec2D, vel Vec2D, size float64) Vec2D {
+	vX := vel.XComponent()
+	vY := vel.YComponent()
+	erec := Rect2D{
+		pos.X - size/2 - 2, pos.Y - size/2 - 2,
+		size + 4, size + 4}
+	for y := 0; y < w.dm.Height(); y++ {
+		for x := 0; x < w.dm.Width(); x++ {
+			if !w.dm.CellHasObstacle(x, y) {
+				continue
+			}
+			orec := w.dm.CellRect(Position{X: x, Y: y})
+			if orec.Overlaps(erec.Add(vX)) {
+				dxL := orec.X - (pos.X + size/2)
+				dxR := pos.X - (orec.X + orec.W + size/2)
+				if dxL > 0 {
+					vX = vX.Truncate(dxL * 0.2)
+				} else if dxR > 0 {
+					vX = vX.Truncate(dxR * 0.2)
+				}
+			}
+			if orec.Overlaps(erec.Add(vX)) {
+				vX = Vec2D{0, 0}
+			}
+			if orec.Overlaps(erec.Add(vY)) {
+				dyD := orec.Y - (pos.Y + size/2)
+				dyU := pos.Y - (orec.Y + orec.H + size/2)
+				if dyD > 0 {
+					vY = vY.Truncate(dyD * 0.2)
+				} else if dyU > 0 {
+					vY = vY.Truncate(dyU * 0.2)
+				}
+			}
+			if orec.Overlaps(erec.Add(vY)) {
+				vY = Vec2D{0, 0}
+			}
+		}
+	}
+	return vX.Add(vY)
+}
+
+func collisionWorld(nObstacles int) *World {
+	w := NewHeadlessWorld(32, 32, 20, 1)
+	for i := 0; i < nObstacles; i++ {
+		w.AddObstacle(Position{X: w.rng.Intn(32), Y: w.rng.Intn(32)})
+	}
+	return w
+}
+
+func TestCollideMatchesBruteForce(t *testing.T) {
+	w := collisionWorld(300)
+	rng := rand.New(rand.NewSource(2))
+	size := w.dm.CellSize() * float64(w.dm.Width())
+	for i := 0; i < 20000; i++ {
+		pos := Vec2D{rng.Float64() * size, rng.Float64() * size}
+		// include velocities larger than a cell
+		vel := Vec2D{
+			(rng.Float64()*2 - 1) * 30,
+			(rng.Float64()*2 - 1) * 30}
+		for _, sz := range []float64{ENTITYSZ, POINTSZ} {
+			got := w.Collide(pos, vel, sz)
+			want := collideAll(w, pos, vel, sz)
+			if got != want {
+				t.Fatalf("pos %v vel %v size %v: got %v, want %v",
+					pos, vel, sz, got, want)
+			}
+		}
+	}
+}
+
+func TestCollideSlides(t *testing.T) {
+	w := NewHeadlessWorld(10, 10, 20, 1)
+	for y := 0; y < 10; y++ {
+		w.AddObstacle(Position{X: 5, Y: y})
+	}
+	// right up against the wall to its right: the x component is stopped
+	// but the y component is untouched
+	pos := Vec2D{100 - ENTITYSZ/2 - 2.5, 50}
+	v := w.Collide(pos, Vec2D{3, 2}, ENTITYSZ)
+	if v.X != 0 || v.Y != 2 {
+		t.Fatalf("expected to slide along the wall, got %v", v)
+	}
+	// approaching the wall, the x component is slowed
+	pos = Vec2D{100 - ENTITYSZ/2 - 5, 50}
+	v = w.Collide(pos, Vec2D{3, 0}, ENTITYSZ)
+	if v.X <= 0 || v.X >= 3 {
+		t.Fatalf("expected to slow near the wall, got %v", v)
+	}
+}
+
+func benchmarkCollide(b *testing.B,
+	collide func(*World, Vec2D, Vec2D, float64) Vec2D) {
+	w := collisionWorld(400)
+	rng := rand.New(rand.NewSource(2))
+	size := w.dm.CellSize() * float64(w.dm.Width())
+	pos := make([]Vec2D, 256)
+	for i := range pos {
+		pos[i] = Vec2D{rng.Float64() * size, rng.Float64() * size}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collide(w, pos[i%len(pos)], Vec2D{2, -2}, ENTITYSZ)
+	}
+}
+
+func BenchmarkCollideGrid(b *testing.B) {
+	benchmarkCollide(b, (*World).Collide)
+}
+
+func BenchmarkCollideAllObstacles(b *testing.B) {
+	benchmarkCollide(b, collideAll)
+}
@@ -1,5 +1,9 @@
 package main
 
+import (
+	"time"
+)
+
 const GRID_WORLD_DIMENSION = 1024
 const GRID_CELL_DIMENSION = GRID_WORLD_DIMENSION / (ENTITYSZ * 2)
 
@@ -7,6 +11,15 @@ const WINDOW_WIDTH = 640
 const WINDOW_HEIGHT = 640
 const FONTSZ = 16
 
+// length of a simulation tick, and how often the diffusion runs
+const SIM_DT = time.Second / (2 * FPS)
+const DIFFUSION_PERIOD = 16 * time.Millisecond
+
+// the most Jacobi steps run per diffusion tick, and the largest change to
+// any cell at which the field counts as settled
+const DIFFUSION_MAX_ITERATIONS = 32
+const DIFFUSION_TOLERANCE = 1e-6
+
 const GRIDCELL_PX_W = WINDOW_WIDTH / GRID_CELL_DIMENSION
 const GRIDCELL_WORLD_W = GRID_WORLD_DIMENSION / GRID_CELL_DIMENSION
 const GRIDCELL_PX_H = WINDOW_HEIGHT / GRID_CELL_DIMENSION
@@ -2,28 +2,68 @@ package main
 
 type ControlMode int
 
-const N_MODES = 3
+const N_MODES = 4
 
 const (
 	MODE_PLACING_WAYPOINT = 0
 	MODE_PLACING_OBSTACLE = iota
 	MODE_PLACING_CHASER   = iota
+	MODE_FLOW_FIELD       = iota
 )
 
 var MODENAMES []string = []string{
 	"MODE_PLACING_WAYPOINT",
 	"MODE_PLACING_OBSTACLE",
 	"MODE_PLACING_CHASER",
+	"MODE_FLOW_FIELD",
+}
+
+type SearchMode int
+
+const N_SEARCHES = 4
+
+const (
+	SEARCH_ASTAR = 0
+	SEARCH_JPS   = iota
+	SEARCH_THETA = iota
+	// D* Lite, which repairs the path when obstacles change
+	SEARCH_DSTAR_LITE = iota
+)
+
+var SEARCHNAMES []string = []string{
+	"SEARCH_ASTAR",
+	"SEARCH_JPS",
+	"SEARCH_THETA",
+	"SEARCH_DSTAR_LITE",
 }
 
 type Controls struct {
-	mode ControlMode
+	mode   ControlMode
+	search SearchMode
+	brush  BrushMode
+	smooth SmoothMode
 }
 
 func NewControls() *Controls {
-	return &Controls{mode: MODE_PLACING_WAYPOINT}
+	return &Controls{
+		mode:   MODE_PLACING_WAYPOINT,
+		search: SEARCH_ASTAR,
+		brush:  BRUSH_POINT,
+		smooth: SMOOTH_NONE}
 }
 
 func (c *Controls) ToggleMode() {
 	c.mode = (c.mode + 1) % N_MODES
 }
+
+func (c *Controls) ToggleSearch() {
+	c.search = (c.search + 1) % N_SEARCHES
+}
+
+func (c *Controls) ToggleSmooth() {
+	c.smooth = (c.smooth + 1) % N_SMOOTHS
+}
+
+func (c *Controls) ToggleBrush() {
+	c.brush = (c.brush + 1) % N_BRUSHES
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// the in-place Gauss-Seidel sweep DiffusionMap used before the Jacobi
+// step, kept here to benchmark against
+func diffuseLayerGaussSeidel(m *DiffusionMap, l *ScentLayer) {
+
+	for i, v := range l.pinned {
+		l.d[i] = v
+	}
+
+	var avgOfNeighbors = func(x int, y int) float64 {
+		neumann := [][2]int{
+			[2]int{-1, 0},
+			[2]int{1, 0},
+			[2]int{0, -1},
+			[2]int{0, 1},
+		}
+		sum := 0.0
+		n := 0.0
+		for _, neu := range neumann {
+			ox := x + neu[0]
+			oy := y + neu[1]
+			if m.InGrid(ox, oy) {
+				sum += l.d[m.ix(ox, oy)]
+				n++
+			}
+		}
+		return sum / n
+	}
+
+	for x := 0; x < m.w; x++ {
+		for y := 0; y < m.h; y++ {
+			i := m.ix(x, y)
+			if _, ok := l.pinned[i]; ok {
+				// don't diffuse the source points
+				continue
+			}
+			if m.os[i] {
+				// obstacles have value zero
+				l.d[i] = 0.0
+				continue
+			}
+			l.d[i] = avgOfNeighbors(x, y)
+			l.d[i] *= l.Attenuation
+		}
+	}
+}
+
+var benchSizes = []int{42, 128, 256, 512}
+
+func benchMap(dim int) *DiffusionMap {
+	m := NewDiffusionMap(nil, dim, dim, 10, nil)
+	for i := 0; i < dim*dim/20; i++ {
+		m.AddObstacle(Position{X: (i * 7919) % dim, Y: (i * 104729) % dim})
+	}
+	m.AddSource(LAYER_PLAYER, Position{X: dim / 2, Y: dim / 2}, 1.0, 1.0)
+	return m
+}
+
+func BenchmarkDiffuseGaussSeidel(b *testing.B) {
+	for _, dim := range benchSizes {
+		b.Run(fmt.Sprintf("%d", dim), func(b *testing.B) {
+			m := benchMap(dim)
+			l := m.Layer(LAYER_PLAYER)
+			l.pinSources()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				diffuseLayerGaussSeidel(m, l)
+			}
+		})
+	}
+}
+
+func BenchmarkDiffuseJacobi(b *testing.B) {
+	for _, dim := range benchSizes {
+		b.Run(fmt.Sprintf("%d", dim), func(b *testing.B) {
+			m := benchMap(dim)
+			l := m.Layer(LAYER_PLAYER)
+			l.pinSources()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.diffuseLayer(l)
+			}
+		})
+	}
+}
+
+func BenchmarkDiffuseJacobiSerial(b *testing.B) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	BenchmarkDiffuseJacobi(b)
+}
@@ -1,85 +1,186 @@
 package main
 
 import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 	"github.com/veandco/go-sdl2/sdl"
-	"time"
+	"math"
+	"runtime"
+	"sync"
 )
 
+// grids are only split across goroutines into bands of at least this many
+// rows, below which the goroutine overhead isn't worth it
+const DIFFUSION_MIN_BAND_ROWS = 16
+
 type DiffusionMap struct {
-	// values of the diffusion field
-	d [GRID_CELL_DIMENSION][GRID_CELL_DIMENSION]float64
-	// ticker used to time updates to the map (since it can be expensive)
-	tick *time.Ticker
-	// whether an obstacle exists at that point in the field
-	os [GRID_CELL_DIMENSION][GRID_CELL_DIMENSION]bool
+	// dimensions of the grid in cells
+	w, h int
+	// world-space width (and height) of a cell
+	cellSize float64
+	// named diffusion fields, in the order they were added
+	layers []*ScentLayer
+	// name of the layer drawn by UpdateTexture
+	shown string
+	// whether an obstacle exists at that point in the field, indexed by
+	// ix(x, y)
+	os []bool
+	// incremented whenever os changes, so layers know to re-diffuse
+	obstacleGen int
 	// renderer reference
 	r *sdl.Renderer
 	// screen texture
 	st *sdl.Texture
 }
 
+// NewDiffusionMap creates a w x h cell map whose cells are cellSize world
+// units across. r may be nil, in which case the map has no texture and
+// UpdateTexture does nothing (useful for running the diffusion headless)
 func NewDiffusionMap(
-	r *sdl.Renderer, obstacles *[]Position, tick time.Duration) *DiffusionMap {
-	st, err := r.CreateTexture(
-		sdl.PIXELFORMAT_RGBA8888,
-		sdl.TEXTUREACCESS_TARGET,
-		WINDOW_WIDTH,
-		WINDOW_HEIGHT)
-	st.SetBlendMode(sdl.BLENDMODE_BLEND)
-	if err != nil {
-		panic(err)
-	}
+	r *sdl.Renderer,
+	w int, h int, cellSize float64,
+	obstacles []Position) *DiffusionMap {
 
 	dm := DiffusionMap{
-		tick: time.NewTicker(tick),
-		r:    r,
-		st:   st}
+		w:        w,
+		h:        h,
+		cellSize: cellSize,
+		os:       make([]bool, w*h),
+		shown:    LAYER_PLAYER,
+		r:        r}
+
+	if r != nil {
+		st, err := r.CreateTexture(
+			sdl.PIXELFORMAT_RGBA8888,
+			sdl.TEXTUREACCESS_TARGET,
+			WINDOW_WIDTH,
+			WINDOW_HEIGHT)
+		if err != nil {
+			panic(err)
+		}
+		st.SetBlendMode(sdl.BLENDMODE_BLEND)
+		dm.st = st
+	}
+
+	dm.Layer(LAYER_PLAYER)
 
-	for _, o := range *obstacles {
+	for _, o := range obstacles {
 		dm.AddObstacle(o)
 	}
 
 	return &dm
 }
 
-func (m *DiffusionMap) ClearObstacles() {
-	for x := 0; x < GRID_CELL_DIMENSION; x++ {
-		for y := 0; y < GRID_CELL_DIMENSION; y++ {
-			m.os[x][y] = false
+// index into the flat backing slices (row-major)
+func (m *DiffusionMap) ix(x int, y int) int {
+	return y*m.w + x
+}
+
+func (m *DiffusionMap) Width() int {
+	return m.w
+}
+
+func (m *DiffusionMap) Height() int {
+	return m.h
+}
+
+func (m *DiffusionMap) CellSize() float64 {
+	return m.cellSize
+}
+
+// the layer with the given name, created if it doesn't exist yet
+func (m *DiffusionMap) Layer(name string) *ScentLayer {
+	for _, l := range m.layers {
+		if l.Name == name {
+			return l
 		}
 	}
+	l := NewScentLayer(m, name)
+	m.layers = append(m.layers, l)
+	return l
+}
+
+func (m *DiffusionMap) Layers() []*ScentLayer {
+	return m.layers
+}
+
+// add a source to the named layer
+func (m *DiffusionMap) AddSource(
+	layer string, pos Position,
+	strength float64, decay float64) *DiffusionSource {
+	return m.Layer(layer).AddSource(pos, strength, decay)
+}
+
+// the weighted sum of the layers at x, y (layers without a weight, or
+// which don't exist, contribute nothing)
+func (m *DiffusionMap) Combined(weights ScentWeights, x int, y int) float64 {
+	sum := 0.0
+	for _, l := range m.layers {
+		if w, ok := weights[l.Name]; ok {
+			sum += w * l.At(x, y)
+		}
+	}
+	return sum
+}
+
+// the layer drawn by UpdateTexture
+func (m *DiffusionMap) ShownLayer() string {
+	return m.shown
+}
+
+// cycle which layer is drawn by UpdateTexture
+func (m *DiffusionMap) ToggleShownLayer() {
+	if len(m.layers) == 0 {
+		return
+	}
+	next := 0
+	for i, l := range m.layers {
+		if l.Name == m.shown {
+			next = (i + 1) % len(m.layers)
+		}
+	}
+	m.shown = m.layers[next].Name
+	m.UpdateTexture()
+}
+
+func (m *DiffusionMap) ClearObstacles() {
+	for i := range m.os {
+		m.os[i] = false
+	}
+	m.obstacleGen++
 }
 
 func (m *DiffusionMap) AddObstacle(o Position) {
-	m.os[o.X][o.Y] = true
+	m.os[m.ix(o.X, o.Y)] = true
+	m.obstacleGen++
+}
+
+func (m *DiffusionMap) RemoveObstacle(o Position) {
+	m.os[m.ix(o.X, o.Y)] = false
+	m.obstacleGen++
 }
 
 func (m *DiffusionMap) UpdateTexture() {
+	if m.r == nil {
+		return
+	}
 	m.r.SetRenderTarget(m.st)
 	defer m.r.SetRenderTarget(nil)
 
 	m.r.SetDrawColor(0, 0, 0, 0)
 	m.r.Clear()
-	for x := 0; x < GRID_CELL_DIMENSION; x++ {
-		for y := 0; y < GRID_CELL_DIMENSION; y++ {
-			val := uint8(255 * m.d[x][y])
+	l := m.Layer(m.shown)
+	for x := 0; x < m.w; x++ {
+		for y := 0; y < m.h; y++ {
+			val := uint8(255 * l.At(x, y))
 			var c sdl.Color
 			if m.CellHasObstacle(x, y) {
 				c = sdl.Color{R: val, G: 0, B: 0}
 			} else {
 				c = sdl.Color{R: val, G: val, B: val}
 			}
-			drawRect(m.r,
-				Rect2D{
-					float64(x * GRIDCELL_WORLD_W),
-					float64(y * GRIDCELL_WORLD_H),
-					GRIDCELL_WORLD_W,
-					GRIDCELL_WORLD_H},
-				c,
-			)
-			drawPoint(m.r,
-				Vec2D{float64(x*GRIDCELL_WORLD_W + GRIDCELL_WORLD_W/2),
-					float64(y*GRIDCELL_WORLD_H + GRIDCELL_WORLD_H/2)},
+			p := Position{X: x, Y: y}
+			drawRect(m.r, m.CellRect(p), c)
+			drawPoint(m.r, m.ToWorldSpace(p),
 				sdl.Color{R: 255, G: 255, B: 255}, 1)
 
 		}
@@ -87,78 +188,221 @@ func (m *DiffusionMap) UpdateTexture() {
 }
 
 func (m *DiffusionMap) CellHasObstacle(x int, y int) bool {
-	return m.os[x][y]
+	return m.os[m.ix(x, y)]
 }
 
+func (m *DiffusionMap) Passable(x int, y int) bool {
+	return !m.os[m.ix(x, y)]
+}
+
+func (m *DiffusionMap) Cost(x int, y int) int {
+	return 1
+}
+
+// diagonals flanked by an obstacle are left out, same as GreaterNeighbor
+func (m *DiffusionMap) Neighbors(p Position, buf []Position) []Position {
+	return gridpath.AppendNeighbors(m, p, buf, false)
+}
+
+// the cell containing p, which may lie outside the grid
+func (m *DiffusionMap) CellOf(p Vec2D) Position {
+	return Position{X: int(p.X / m.cellSize), Y: int(p.Y / m.cellSize)}
+}
+
+// the cell containing p, clamped to the grid
 func (m *DiffusionMap) ToGridSpace(p Vec2D) Position {
-	x := int(p.X / GRIDCELL_WORLD_W)
-	y := int(p.Y / GRIDCELL_WORLD_H)
-	if x > GRID_CELL_DIMENSION-1 {
-		x = GRID_CELL_DIMENSION - 1
+	x := int(p.X / m.cellSize)
+	y := int(p.Y / m.cellSize)
+	if x > m.w-1 {
+		x = m.w - 1
 	}
 	if x < 0 {
 		x = 0
 	}
-	if y > GRID_CELL_DIMENSION-1 {
-		y = GRID_CELL_DIMENSION - 1
+	if y > m.h-1 {
+		y = m.h - 1
 	}
 	if y < 0 {
 		y = 0
 	}
-	return Position{x, y}
+	return Position{X: x, Y: y}
 }
 
 func (m *DiffusionMap) ToWorldSpace(p Position) Vec2D {
 	return Vec2D{
-		float64(p.X*GRIDCELL_WORLD_W + GRIDCELL_WORLD_W/2),
-		float64(p.Y*GRIDCELL_WORLD_H + GRIDCELL_WORLD_H/2)}
+		float64(p.X)*m.cellSize + m.cellSize/2,
+		float64(p.Y)*m.cellSize + m.cellSize/2}
+}
+
+// the world-space rect covered by the cell at p
+func (m *DiffusionMap) CellRect(p Position) Rect2D {
+	return Rect2D{
+		float64(p.X) * m.cellSize,
+		float64(p.Y) * m.cellSize,
+		m.cellSize,
+		m.cellSize}
+}
+
+// the range of cells (inclusive, clamped to the grid) whose rects might
+// overlap r, touching edges included
+func (m *DiffusionMap) CellRange(r Rect2D) (x0 int, y0 int, x1 int, y1 int) {
+	x0 = int(math.Floor(r.X/m.cellSize)) - 1
+	y0 = int(math.Floor(r.Y/m.cellSize)) - 1
+	x1 = int(math.Floor((r.X + r.W) / m.cellSize))
+	y1 = int(math.Floor((r.Y + r.H) / m.cellSize))
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > m.w-1 {
+		x1 = m.w - 1
+	}
+	if y1 > m.h-1 {
+		y1 = m.h - 1
+	}
+	return x0, y0, x1, y1
 }
 
 func (m *DiffusionMap) InGrid(x int, y int) bool {
-	return x >= 0 && x < GRID_CELL_DIMENSION &&
-		y >= 0 && y < GRID_CELL_DIMENSION
+	return x >= 0 && x < m.w &&
+		y >= 0 && y < m.h
 }
 
-func (m *DiffusionMap) Diffuse(pos Vec2D) {
+// what a call to Diffuse did
+type DiffusionResult struct {
+	// the most steps any layer ran
+	Iterations int
+	// the largest change to any cell in the last step of any layer
+	Residual float64
+	// true if every layer was already settled and nothing had changed
+	Skipped bool
+}
 
-	init := m.ToGridSpace(pos)
-	m.d[init.X][init.Y] = 1.0
+// step each layer until the largest change to any of its cells in a step
+// falls to tolerance or maxIterations steps have run. Sources are pinned
+// (and decayed) once per call. A layer is left alone if it settled last
+// time and neither its sources nor the obstacles have changed since
+func (m *DiffusionMap) Diffuse(
+	maxIterations int, tolerance float64) DiffusionResult {
 
-	var avgOfNeighbors = func(x int, y int) float64 {
-		neumann := [][2]int{
-			[2]int{-1, 0},
-			[2]int{1, 0},
-			[2]int{0, -1},
-			[2]int{0, 1},
+	res := DiffusionResult{Skipped: true}
+	for _, l := range m.layers {
+		sourcesChanged := l.pinSources()
+		if l.settled && !sourcesChanged && l.obstacleGen == m.obstacleGen {
+			continue
 		}
-		sum := 0.0
-		n := 0.0
-		for _, neu := range neumann {
-			ox := x + neu[0]
-			oy := y + neu[1]
-			if m.InGrid(ox, oy) {
-				sum += m.d[ox][oy]
-				n++
+		res.Skipped = false
+		l.obstacleGen = m.obstacleGen
+		n := 0
+		residual := 0.0
+		for n < maxIterations {
+			residual = m.diffuseLayer(l)
+			n++
+			if residual <= tolerance {
+				break
 			}
 		}
-		return sum / n
+		l.residual = residual
+		l.settled = residual <= tolerance
+		if n > res.Iterations {
+			res.Iterations = n
+		}
+	}
+	for _, l := range m.layers {
+		if l.residual > res.Residual {
+			res.Residual = l.residual
+		}
+	}
+	if !res.Skipped {
+		m.UpdateTexture()
+	}
+	return res
+}
+
+// one Jacobi step: every cell is computed from the previous values of its
+// neighbours into l.next, which is then swapped with l.d. Since no cell
+// reads a value written during the same step, the result doesn't depend on
+// scan order or on how the rows are split between goroutines. Returns the
+// largest change to any cell
+func (m *DiffusionMap) diffuseLayer(l *ScentLayer) float64 {
+
+	for i, v := range l.pinned {
+		l.d[i] = v
+	}
+
+	bands := runtime.GOMAXPROCS(0)
+	if m.h/bands < DIFFUSION_MIN_BAND_ROWS {
+		bands = m.h / DIFFUSION_MIN_BAND_ROWS
+	}
+	residual := 0.0
+	if bands <= 1 {
+		residual = m.diffuseRows(l, 0, m.h)
+	} else {
+		// each band writes only its own slot, and max doesn't care about
+		// the order the bands finish in
+		residuals := make([]float64, bands)
+		var wg sync.WaitGroup
+		wg.Add(bands)
+		for b := 0; b < bands; b++ {
+			go func(b int, y0 int, y1 int) {
+				residuals[b] = m.diffuseRows(l, y0, y1)
+				wg.Done()
+			}(b, b*m.h/bands, (b+1)*m.h/bands)
+		}
+		wg.Wait()
+		for _, r := range residuals {
+			residual = math.Max(residual, r)
+		}
 	}
 
-	for x := 0; x < GRID_CELL_DIMENSION; x++ {
-		for y := 0; y < GRID_CELL_DIMENSION; y++ {
-			if y == init.Y && x == init.X {
-				// don't diffuse the init point
+	l.d, l.next = l.next, l.d
+	return residual
+}
+
+// compute rows [y0, y1) of l.next from l.d, returning the largest change
+// to any cell
+func (m *DiffusionMap) diffuseRows(
+	l *ScentLayer, y0 int, y1 int) float64 {
+	w := m.w
+	residual := 0.0
+	for y := y0; y < y1; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			if l.isPinned[i] {
+				// don't diffuse the source points
+				l.next[i] = l.d[i]
 				continue
 			}
-			if m.os[x][y] {
+			if m.os[i] {
 				// obstacles have value zero
-				m.d[x][y] = 0.0
+				l.next[i] = 0.0
+				residual = math.Max(residual, l.d[i])
 				continue
 			}
-			m.d[x][y] = avgOfNeighbors(x, y)
-			m.d[x][y] *= 0.998
+			// average of the von Neumann neighbours
+			sum := 0.0
+			n := 0.0
+			if x > 0 {
+				sum += l.d[i-1]
+				n++
+			}
+			if x < w-1 {
+				sum += l.d[i+1]
+				n++
+			}
+			if y > 0 {
+				sum += l.d[i-w]
+				n++
+			}
+			if y < m.h-1 {
+				sum += l.d[i+w]
+				n++
+			}
+			l.next[i] = sum / n * l.Attenuation
+			residual = math.Max(residual, math.Abs(l.next[i]-l.d[i]))
 		}
 	}
-
-	m.UpdateTexture()
+	return residual
 }
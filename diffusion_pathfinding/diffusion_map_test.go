@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"testing"
+)
+
+func TestDiffusionMapSizes(t *testing.T) {
+	for _, dims := range [][2]int{{8, 8}, {42, 42}, {30, 12}} {
+		w, h := dims[0], dims[1]
+		m := NewDiffusionMap(nil, w, h, 10, nil)
+		if m.Width() != w || m.Height() != h {
+			t.Fatalf("expected %dx%d map, got %dx%d", w, h, m.Width(), m.Height())
+		}
+		if !m.InGrid(w-1, h-1) || m.InGrid(w, 0) || m.InGrid(0, h) {
+			t.Fatalf("%dx%d: InGrid bounds are wrong", w, h)
+		}
+		corner := m.ToGridSpace(Vec2D{1e6, 1e6})
+		if corner != (Position{X: w - 1, Y: h - 1}) {
+			t.Fatalf("%dx%d: ToGridSpace clamped to %v", w, h, corner)
+		}
+		p := Position{X: w - 2, Y: h / 2}
+		if m.ToGridSpace(m.ToWorldSpace(p)) != p {
+			t.Fatalf("%dx%d: %v doesn't round-trip through world space", w, h, p)
+		}
+
+		wall := Position{X: w / 2, Y: h / 2}
+		m.AddObstacle(wall)
+		src := Position{X: 1, Y: 1}
+		m.AddSource(LAYER_PLAYER, src, 1.0, 1.0)
+		m.Diffuse(4*(w+h), 0)
+		l := m.Layer(LAYER_PLAYER)
+		if l.At(src.X, src.Y) != 1.0 {
+			t.Fatalf("%dx%d: source cell is %f", w, h, l.At(src.X, src.Y))
+		}
+		if l.At(wall.X, wall.Y) != 0.0 {
+			t.Fatalf("%dx%d: obstacle cell is %f", w, h, l.At(wall.X, wall.Y))
+		}
+		if !(l.At(2, 1) > l.At(3, 1) && l.At(3, 1) > 0) {
+			t.Fatalf("%dx%d: field doesn't fall off away from the source", w, h)
+		}
+	}
+}
+
+func TestScentLayers(t *testing.T) {
+	m := NewDiffusionMap(nil, 20, 20, 10, nil)
+	food := Position{X: 2, Y: 10}
+	danger := Position{X: 17, Y: 10}
+	m.AddSource(LAYER_FOOD, food, 1.0, 1.0)
+	m.AddSource(LAYER_DANGER, danger, 1.0, 1.0)
+	fading := m.AddSource(LAYER_FOOD, Position{X: 10, Y: 2}, 1.0, 0.5)
+	for i := 0; i < 200; i++ {
+		m.Diffuse(1, 0)
+	}
+	for _, s := range m.Layer(LAYER_FOOD).Sources() {
+		if s == fading {
+			t.Fatal("faded source is still in its layer")
+		}
+	}
+	if m.Layer(LAYER_PLAYER).At(10, 10) != 0 {
+		t.Fatal("player layer has a value without any source")
+	}
+
+	w := &World{dm: m}
+	start := m.ToWorldSpace(Position{X: 10, Y: 10})
+	for _, tc := range []struct {
+		weights ScentWeights
+		dx      int
+	}{
+		{ScentWeights{LAYER_FOOD: 1}, -1},
+		{ScentWeights{LAYER_DANGER: 1}, 1},
+		{ScentWeights{LAYER_DANGER: -1}, -1},
+		{ScentWeights{LAYER_FOOD: -1, LAYER_DANGER: 0.2}, 1},
+	} {
+		c := NewChaser(start, w)
+		c.weights = tc.weights
+		next := m.ToGridSpace(*c.GreaterNeighbor())
+		if next.X-10 != tc.dx {
+			t.Fatalf("with weights %v expected to step %d in x, went to %v",
+				tc.weights, tc.dx, next)
+		}
+	}
+
+	c := NewChaser(m.ToWorldSpace(food), w)
+	c.weights = ScentWeights{LAYER_FOOD: 1}
+	if c.GreaterNeighbor() != nil {
+		t.Fatal("chaser sitting on a food source should have arrived")
+	}
+}
+
+func TestDiffuseDeterministic(t *testing.T) {
+	run := func(procs int) []float64 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		m := NewDiffusionMap(nil, 97, 131, 10, nil)
+		for i := 0; i < 400; i++ {
+			m.AddObstacle(Position{X: (i * 31) % 97, Y: (i * 17) % 131})
+		}
+		m.AddSource(LAYER_PLAYER, Position{X: 40, Y: 60}, 1.0, 1.0)
+		m.Diffuse(100, 0)
+		return append([]float64{}, m.Layer(LAYER_PLAYER).d...)
+	}
+	serial := run(1)
+	for _, procs := range []int{2, 3, 8} {
+		d := run(procs)
+		for i := range serial {
+			if d[i] != serial[i] {
+				t.Fatalf("GOMAXPROCS=%d: cell %d is %v, serially %v",
+					procs, i, d[i], serial[i])
+			}
+		}
+	}
+}
+
+func TestDiffuseSymmetric(t *testing.T) {
+	// a source in the middle of an empty grid should produce a field with
+	// the same symmetry as the grid, with no bias toward any corner
+	const dim = 33
+	m := NewDiffusionMap(nil, dim, dim, 10, nil)
+	m.AddSource(LAYER_PLAYER, Position{X: dim / 2, Y: dim / 2}, 1.0, 1.0)
+	m.Diffuse(50, 0)
+	l := m.Layer(LAYER_PLAYER)
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			v := l.At(x, y)
+			for _, o := range []float64{
+				l.At(dim-1-x, y), l.At(x, dim-1-y), l.At(y, x)} {
+				if math.Abs(o-v) > 1e-12 {
+					t.Fatalf("field isn't symmetric at %d, %d", x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestDiffuseConvergence(t *testing.T) {
+	m := NewDiffusionMap(nil, 30, 30, 10, nil)
+	src := m.AddSource(LAYER_PLAYER, Position{X: 5, Y: 5}, 1.0, 1.0)
+
+	res := m.Diffuse(5, 1e-9)
+	if res.Iterations != 5 || res.Residual <= 1e-9 || res.Skipped {
+		t.Fatalf("expected the budget to run out, got %+v", res)
+	}
+	res = m.Diffuse(100000, 1e-9)
+	if res.Iterations >= 100000 || res.Residual > 1e-9 {
+		t.Fatalf("expected to settle within tolerance, got %+v", res)
+	}
+	res = m.Diffuse(100000, 1e-9)
+	if !res.Skipped || res.Iterations != 0 {
+		t.Fatalf("expected nothing to do once settled, got %+v", res)
+	}
+
+	src.Pos = Position{X: 6, Y: 5}
+	if res = m.Diffuse(1, 1e-9); res.Skipped {
+		t.Fatal("moving the source should wake the layer up")
+	}
+	m.Diffuse(100000, 1e-9)
+	m.AddObstacle(Position{X: 20, Y: 20})
+	if res = m.Diffuse(1, 1e-9); res.Skipped {
+		t.Fatal("adding an obstacle should wake the layer up")
+	}
+}
@@ -13,6 +13,7 @@ func (g *Game) DrawGrid() {
 	g.DrawObstacles()
 	g.DrawEntityAndPath()
 	g.DrawChasers()
+	g.DrawFlowField()
 }
 
 func (g *Game) DrawChasers() {
@@ -24,6 +25,26 @@ func (g *Game) DrawChasers() {
 	}
 }
 
+func (g *Game) DrawFlowField() {
+	color := sdl.Color{R: 255, G: 160, B: 0}
+	if goal, ok := g.w.flow.Goal(); ok {
+		drawPoint(g.r, g.w.dm.ToWorldSpace(goal), color, POINTSZ)
+		if g.showData && g.c.mode == MODE_FLOW_FIELD {
+			for y := 0; y < g.w.dm.Height(); y++ {
+				for x := 0; x < g.w.dm.Width(); x++ {
+					p := Position{X: x, Y: y}
+					d := g.w.flow.Direction(p)
+					drawVector(g.r, g.w.dm.ToWorldSpace(p),
+						d.Scale(g.w.dm.cellSize/3), color)
+				}
+			}
+		}
+	}
+	for _, a := range g.w.flowAgents {
+		drawPoint(g.r, a.pos, color, POINTSZ/2)
+	}
+}
+
 func (g *Game) DrawEntityAndPath() {
 
 	if g.w.e != nil {
@@ -57,15 +78,9 @@ func (g *Game) DrawEntityAndPath() {
 }
 
 func (g *Game) DrawObstacles() {
-	for _, o := range g.w.obstacles {
-		oc := Vec2D{
-			float64(GRIDCELL_WORLD_W*o.X + GRIDCELL_WORLD_W/2),
-			float64(GRIDCELL_WORLD_H*o.Y + GRIDCELL_WORLD_H/2)}
-		orec := Rect2D{
-			float64(GRIDCELL_WORLD_W * o.X),
-			float64(GRIDCELL_WORLD_H * o.Y),
-			GRIDCELL_WORLD_W,
-			GRIDCELL_WORLD_H}
+	for _, o := range g.w.obstacles.Cells() {
+		oc := g.w.dm.ToWorldSpace(o)
+		orec := g.w.dm.CellRect(o)
 		bodyColor := sdl.Color{R: 255, G: 0, B: 0}
 		pointColor := sdl.Color{R: 0, G: 0, B: 0}
 		if g.w.e != nil && g.w.e.moveTarget != nil {
@@ -73,8 +88,8 @@ func (g *Game) DrawObstacles() {
 			d := toward.Magnitude()
 			ovec := oc.Sub(g.w.e.pos)
 			oIsAhead := g.w.e.vel.Project(ovec) > 0
-			closeToO := ovec.Magnitude() < GRIDCELL_WORLD_W*1.5
-			if d > GRIDCELL_WORLD_W && oIsAhead && closeToO {
+			closeToO := ovec.Magnitude() < g.w.dm.cellSize*1.5
+			if d > g.w.dm.cellSize && oIsAhead && closeToO {
 				bodyColor = sdl.Color{R: 128, G: 0, B: 0}
 			}
 		}
@@ -11,7 +11,12 @@ type Entity struct {
 	vel        Vec2D
 	steer      Vec2D
 	moveTarget *Vec2D
-	path       []Vec2D
+	// the waypoints still to walk to moveTarget, the next one first
+	path []Vec2D
+	// how the path to moveTarget was searched for and smoothed, so it can
+	// be replanned the same way
+	search SearchMode
+	smooth SmoothMode
 }
 
 func NewEntity(pos Vec2D, w *World) *Entity {
@@ -35,9 +40,9 @@ func (e *Entity) UpdateVel() {
 				nextPathPoint = e.moveTarget
 				break
 			}
-			nextPathPoint = &e.path[len(e.path)-1]
-			if e.pos.Sub(*nextPathPoint).Magnitude() < GRIDCELL_WORLD_W/4 {
-				e.path = e.path[:len(e.path)-1]
+			nextPathPoint = &e.path[0]
+			if e.pos.Sub(*nextPathPoint).Magnitude() < e.w.dm.cellSize/4 {
+				e.path = e.path[1:]
 				nextPathPoint = nil
 				continue
 			}
@@ -52,49 +57,5 @@ func (e *Entity) UpdateVel() {
 }
 
 func (e *Entity) Move() {
-	vel := e.vel
-	vX := e.vel.XComponent()
-	vY := e.vel.YComponent()
-	for _, o := range e.w.obstacles {
-		orec := Rect2D{
-			float64(GRIDCELL_WORLD_W * o.X),
-			float64(GRIDCELL_WORLD_H * o.Y),
-			GRIDCELL_WORLD_W,
-			GRIDCELL_WORLD_H}
-		erec := Rect2D{
-			e.pos.X - ENTITYSZ/2 - 2, e.pos.Y - ENTITYSZ/2 - 2,
-			ENTITYSZ + 4, ENTITYSZ + 4}
-		erecX := erec.Add(vX)
-		if orec.Overlaps(erecX) {
-			dxL := orec.X - (e.pos.X + ENTITYSZ/2)
-			dxR := e.pos.X - (orec.X + orec.W + ENTITYSZ/2)
-			if dxL > 0 {
-				// we are to the left
-				vX = vX.Truncate(dxL * 0.2)
-			} else if dxR > 0 {
-				// we are to the right
-				vX = vX.Truncate(dxR * 0.2)
-			}
-		}
-		if orec.Overlaps(erec.Add(vX)) {
-			vX = Vec2D{0, 0}
-		}
-		erecY := erec.Add(vY)
-		if orec.Overlaps(erecY) {
-			dyD := orec.Y - (e.pos.Y + ENTITYSZ/2)
-			dyU := e.pos.Y - (orec.Y + orec.H + ENTITYSZ/2)
-			if dyD > 0 {
-				// we are down
-				vY = vY.Truncate(dyD * 0.2)
-			} else if dyU > 0 {
-				// we are up
-				vY = vY.Truncate(dyU * 0.2)
-			}
-		}
-		if orec.Overlaps(erec.Add(vY)) {
-			vY = Vec2D{0, 0}
-		}
-	}
-	vel = vX.Add(vY)
-	e.pos = e.pos.Add(vel)
+	e.pos = e.pos.Add(e.w.Collide(e.pos, e.vel, ENTITYSZ))
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+)
+
+// a crowd member which steers by sampling the World's flow field rather
+// than searching for a path of its own
+type FlowAgent struct {
+	w *World
+
+	pos        Vec2D
+	vel        Vec2D
+	steer      Vec2D
+	moveTarget *Vec2D
+}
+
+func NewFlowAgent(pos Vec2D, w *World) *FlowAgent {
+	return &FlowAgent{
+		w:   w,
+		pos: pos,
+	}
+}
+
+// the centre of the cell the flow field says to step to next, or nil if
+// the agent is at the goal's cell or can't reach it
+func (a *FlowAgent) NextCell() *Vec2D {
+	cpos := a.w.dm.ToGridSpace(a.pos)
+	next := a.w.flow.Next(cpos)
+	if next == cpos {
+		return nil
+	}
+	p := a.w.dm.ToWorldSpace(next)
+	return &p
+}
+
+func (a *FlowAgent) Update() {
+	a.UpdateVel()
+	a.Move()
+}
+
+func (a *FlowAgent) UpdateVel() {
+	if a.moveTarget == nil ||
+		a.pos.Sub(*a.moveTarget).Magnitude() < a.w.dm.cellSize/4 {
+		a.moveTarget = a.NextCell()
+	}
+	if a.moveTarget == nil {
+		// nowhere to go, so coast to a stop
+		a.vel = a.vel.Scale(0.8)
+		return
+	}
+	toward := a.moveTarget.Sub(a.pos)
+	if toward.Magnitude() == 0 {
+		return
+	}
+	a.steer = toward.Unit()
+	angle := a.vel.AngleBetween(a.steer)
+	maxVel := (MOVESPEED / 2) * (1 - 0.9*(angle/math.Pi))
+	a.vel = a.vel.Add(a.steer).Truncate(maxVel)
+}
+
+func (a *FlowAgent) Move() {
+	a.pos = a.pos.Add(a.w.Collide(a.pos, a.vel, POINTSZ))
+}
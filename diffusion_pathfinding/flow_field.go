@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+)
+
+// the integration value of cells from which the goal can't be reached
+const FLOW_UNREACHABLE = -1
+
+// a flow field toward a single goal cell: one Dijkstra search out from the
+// goal gives every cell its cost to the goal (the integration field) and
+// the neighbour its cheapest path steps to (the direction field), so any
+// number of agents heading for the goal can steer by looking up their cell
+// instead of each searching for a path
+type FlowField struct {
+	dm *DiffusionMap
+	pc *gridpath.PathComputer
+
+	goal    Position
+	hasGoal bool
+	// cost of the cheapest path from each cell to the goal, indexed by
+	// dm.ix(x, y), or FLOW_UNREACHABLE
+	cost []int
+	// the cell each cell's path to the goal steps to next, indexed by
+	// dm.ix(x, y). A cell is its own next at the goal and where the goal
+	// can't be reached
+	next []Position
+	// the DiffusionMap's obstacleGen when the field was last computed
+	obstacleGen int
+}
+
+func NewFlowField(dm *DiffusionMap) *FlowField {
+	return &FlowField{
+		dm:   dm,
+		pc:   gridpath.NewPathComputer(dm, dm.Width(), dm.Height()),
+		cost: make([]int, dm.Width()*dm.Height()),
+		next: make([]Position, dm.Width()*dm.Height()),
+	}
+}
+
+// point the field at goal, recomputing it
+func (f *FlowField) SetGoal(goal Position) {
+	f.goal = goal
+	f.hasGoal = true
+	f.Compute()
+}
+
+func (f *FlowField) Goal() (Position, bool) {
+	return f.goal, f.hasGoal
+}
+
+// recompute the field if the obstacles have changed since it was last
+// computed. Returns whether it was recomputed
+func (f *FlowField) Update() bool {
+	if !f.hasGoal || f.obstacleGen == f.dm.obstacleGen {
+		return false
+	}
+	f.Compute()
+	return true
+}
+
+// fill in the integration and direction fields from the goal
+func (f *FlowField) Compute() {
+	f.obstacleGen = f.dm.obstacleGen
+	for i := range f.cost {
+		f.cost[i] = FLOW_UNREACHABLE
+	}
+	for y := 0; y < f.dm.Height(); y++ {
+		for x := 0; x < f.dm.Width(); x++ {
+			f.next[f.dm.ix(x, y)] = Position{X: x, Y: y}
+		}
+	}
+	if !f.hasGoal || !f.dm.Passable(f.goal.X, f.goal.Y) {
+		return
+	}
+	// the grid's neighbours and costs are symmetric, so the path Dijkstra
+	// finds from the goal to a cell, walked backward, is a cheapest path
+	// from the cell to the goal
+	f.pc.Dijkstra(f.goal)
+	for y := 0; y < f.dm.Height(); y++ {
+		for x := 0; x < f.dm.Width(); x++ {
+			if !f.pc.Reached(x, y) {
+				continue
+			}
+			i := f.dm.ix(x, y)
+			f.cost[i] = f.pc.G[x][y]
+			if x != f.goal.X || y != f.goal.Y {
+				f.next[i] = f.pc.From[x][y]
+			}
+		}
+	}
+}
+
+// the cost of the cheapest path from x, y to the goal, or FLOW_UNREACHABLE
+func (f *FlowField) Cost(x int, y int) int {
+	return f.cost[f.dm.ix(x, y)]
+}
+
+// the cell to step to from p on the way to the goal (p itself at the goal,
+// or if the goal can't be reached from p)
+func (f *FlowField) Next(p Position) Position {
+	return f.next[f.dm.ix(p.X, p.Y)]
+}
+
+// the unit direction to move in from the cell p, or the zero vector if
+// there's nowhere to go
+func (f *FlowField) Direction(p Position) Vec2D {
+	n := f.Next(p)
+	if n == p {
+		return Vec2D{0, 0}
+	}
+	return Vec2D{float64(n.X - p.X), float64(n.Y - p.Y)}.Unit()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"testing"
+)
+
+func TestFlowFieldFollowsCheapestPaths(t *testing.T) {
+	w := NewHeadlessWorld(20, 20, GRIDCELL_WORLD_W, 1)
+	for _, o := range wall(10, 0, 15) {
+		w.AddObstacle(o)
+	}
+	goal := Position{X: 15, Y: 5}
+	w.SetFlowGoal(goal)
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			p := Position{X: x, Y: y}
+			if w.dm.CellHasObstacle(x, y) {
+				if w.flow.Cost(x, y) != FLOW_UNREACHABLE {
+					t.Fatalf("obstacle %v has a cost", p)
+				}
+				continue
+			}
+			// the field's cost agrees with A*, and following the direction
+			// field gets to the goal in that many steps' worth of cost
+			path := w.pc.Path(p, goal)
+			if path == nil {
+				t.Fatalf("no A* path from %v", p)
+			}
+			want := w.pc.G[goal.X][goal.Y]
+			if w.flow.Cost(x, y) != want {
+				t.Fatalf("cost at %v is %d, A* found %d",
+					p, w.flow.Cost(x, y), want)
+			}
+			cost := 0
+			for cur := p; cur != goal; {
+				next := w.flow.Next(cur)
+				if next == cur {
+					t.Fatalf("flow from %v stalls at %v", p, cur)
+				}
+				cost += gridpath.StepDistance(cur, next)
+				cur = next
+			}
+			if cost != want {
+				t.Fatalf("following the flow from %v costs %d, want %d",
+					p, cost, want)
+			}
+		}
+	}
+}
+
+func TestFlowFieldRecomputesOnObstacleChange(t *testing.T) {
+	w := NewHeadlessWorld(20, 20, GRIDCELL_WORLD_W, 1)
+	w.SetFlowGoal(Position{X: 15, Y: 5})
+	before := w.flow.Cost(5, 5)
+	if w.flow.Update() {
+		t.Fatal("recomputed with no change")
+	}
+	for _, o := range wall(10, 0, 15) {
+		w.AddObstacle(o)
+	}
+	if !w.flow.Update() {
+		t.Fatal("didn't recompute after obstacles were added")
+	}
+	if w.flow.Cost(5, 5) <= before {
+		t.Fatalf("cost through the wall didn't go up: %d -> %d",
+			before, w.flow.Cost(5, 5))
+	}
+	// walled off entirely
+	for _, o := range wall(10, 16, 19) {
+		w.AddObstacle(o)
+	}
+	w.flow.Update()
+	if w.flow.Cost(5, 5) != FLOW_UNREACHABLE ||
+		w.flow.Next(Position{X: 5, Y: 5}) != (Position{X: 5, Y: 5}) {
+		t.Fatal("cell cut off from the goal still flows")
+	}
+}
+
+func TestFlowAgentsReachGoal(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	for _, o := range wall(15, 0, 24) {
+		w.AddObstacle(o)
+	}
+	for _, p := range []Position{
+		{X: 2, Y: 2}, {X: 5, Y: 20}, {X: 12, Y: 3}, {X: 2, Y: 28}} {
+		w.flowAgents = append(w.flowAgents,
+			NewFlowAgent(w.dm.ToWorldSpace(p), w))
+	}
+	goal := Position{X: 25, Y: 5}
+	w.SetFlowGoal(goal)
+	for i := 0; i < 5000; i++ {
+		w.UpdateAgents()
+	}
+	for i, a := range w.flowAgents {
+		if d := a.pos.Sub(w.dm.ToWorldSpace(goal)).Magnitude(); d > w.dm.cellSize {
+			t.Fatalf("agent %d ended %.1f from the goal, at %v", i, d, a.pos)
+		}
+	}
+}
+
+// a crowd of agents all heading for the same goal on the maze level, either
+// sharing one flow field or each searching for a path with A*
+func benchmarkCrowd(b *testing.B, agents int, flow bool) {
+	l, err := LoadLevel("levels/maze.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := NewHeadlessWorld(l.W, l.H, GRIDCELL_WORLD_W, 1)
+	for _, o := range l.Obstacles {
+		w.AddObstacle(o)
+	}
+	goal := l.Waypoints[0]
+	var starts []Position
+	for y := 0; y < l.H && len(starts) < agents; y++ {
+		for x := 0; x < l.W/3 && len(starts) < agents; x++ {
+			if !w.dm.CellHasObstacle(x, y) {
+				starts = append(starts, Position{X: x, Y: y})
+			}
+		}
+	}
+	// more agents than cells: double up
+	for i := 0; len(starts) < agents; i++ {
+		starts = append(starts, starts[i])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if flow {
+			w.flow.SetGoal(goal)
+			for _, s := range starts {
+				w.flow.Next(s)
+			}
+		} else {
+			for _, s := range starts {
+				w.pc.Path(s, goal)
+			}
+		}
+	}
+}
+
+func BenchmarkCrowd(b *testing.B) {
+	for _, n := range []int{1, 16, 64, 256} {
+		b.Run(fmt.Sprintf("FlowField/%d", n), func(b *testing.B) {
+			benchmarkCrowd(b, n, true)
+		})
+		b.Run(fmt.Sprintf("AStar/%d", n), func(b *testing.B) {
+			benchmarkCrowd(b, n, false)
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"github.com/dt-rush/gamedev-sketchbook/simclock"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
+	"strings"
 	"time"
 )
 
@@ -11,6 +15,23 @@ type Game struct {
 	w  *World
 	c  *Controls
 	ui *UI
+	// drives the world and the rendering
+	clock *simclock.Clock
+	// if non-nil, every command is written to it
+	rec *Recorder
+	// commands still to be replayed, in tick order. While there are any,
+	// input which would produce commands is ignored
+	replay []Command
+	// where s saves the level and o loads it from
+	levelPath string
+	// if set, each search the entity makes is traced and written out under
+	// this prefix (see finishTrace), and traces counts them
+	tracePrefix string
+	traces      int
+	// where the button which started a rect or line brush stroke went
+	// down (nil if no stroke is in progress)
+	strokeStart  *Vec2D
+	strokeButton uint8
 
 	paused   bool
 	showData bool
@@ -19,40 +40,199 @@ type Game struct {
 	f *ttf.Font
 }
 
-func NewGame(r *sdl.Renderer, f *ttf.Font) *Game {
+// seed seeds the World's rng (a replay needs the seed it was recorded with)
+func NewGame(r *sdl.Renderer, f *ttf.Font, seed int64) *Game {
 	g := &Game{r: r, f: f, showData: true}
 
-	g.w = NewWorld(g)
+	g.w = NewWorld(g, seed)
 	g.c = NewControls()
 	g.ui = NewUI(r, f)
+	g.clock = simclock.New(SIM_DT, simclock.RealTime{})
+	// replayed commands go first, so they land before the world updates
+	// on their tick, as they did when recorded
+	g.clock.Every(1, g.applyReplay)
+	g.w.Schedule(g.clock, func() bool { return g.paused })
+	g.clock.EveryDuration(time.Second/FPS, func(int) {
+		sdl.Do(func() {
+			g.r.Clear()
+			g.DrawGrid()
+			g.DrawUI()
+			g.r.Present()
+		})
+	})
 
-	g.ui.UpdateMsg(0, "i: show data, m: toggle mode, p: pause")
-	g.ui.UpdateMsg(1, "g: place random obstacles, c: clear obstacles")
-	g.ui.UpdateMsg(2, fmt.Sprintf("grid dimension: %d", GRID_CELL_DIMENSION))
+	g.ui.UpdateMsg(0, "i: show data, m: toggle mode, a: toggle search, p: pause")
+	g.ui.UpdateMsg(1, "g: place random obstacles, c: clear obstacles, l: cycle layer")
+	g.ui.UpdateMsg(10, "s: save level, o: load level, b: cycle brush, h: cycle smoothing")
+	g.ui.UpdateMsg(11, BRUSHNAMES[g.c.brush])
+	g.ui.UpdateMsg(12, SMOOTHNAMES[g.c.smooth])
+	g.ui.UpdateMsg(2, fmt.Sprintf("grid dimension: %dx%d", g.w.dm.Width(), g.w.dm.Height()))
 	g.ui.UpdateMsg(3, MODENAMES[g.c.mode])
+	g.ui.UpdateMsg(7, SEARCHNAMES[g.c.search])
+	g.ui.UpdateMsg(8, "showing layer: "+g.w.dm.ShownLayer())
 	return g
 }
 
+// write every command from now on to path
+func (g *Game) StartRecording(path string, seed int64) error {
+	rec, err := NewRecorder(path, seed)
+	if err != nil {
+		return err
+	}
+	g.rec = rec
+	g.ui.UpdateMsg(9, "recording to "+path)
+	return nil
+}
+
+// feed cmds back in on their ticks instead of taking input
+func (g *Game) StartReplay(cmds []Command) {
+	g.replay = cmds
+	g.ui.UpdateMsg(9, fmt.Sprintf("replaying %d commands", len(cmds)))
+}
+
+func (g *Game) Close() {
+	if g.rec != nil {
+		g.rec.Close()
+	}
+}
+
+// record cmd as happening on the upcoming tick and apply it (input is
+// ignored while replaying)
+func (g *Game) Do(cmd Command) {
+	if len(g.replay) > 0 {
+		return
+	}
+	cmd.Tick = g.clock.Tick
+	g.record(cmd)
+	g.Apply(cmd)
+}
+
+func (g *Game) record(cmd Command) {
+	if g.rec == nil {
+		return
+	}
+	if err := g.rec.Record(cmd); err != nil {
+		g.ui.UpdateMsg(9, "recording failed: "+err.Error())
+		g.rec = nil
+	}
+}
+
+func (g *Game) applyReplay(tick int) {
+	if len(g.replay) == 0 {
+		return
+	}
+	for len(g.replay) > 0 && g.replay[0].Tick <= tick {
+		// recording a replay carries it over into the new recording
+		g.record(g.replay[0])
+		g.Apply(g.replay[0])
+		g.replay = g.replay[1:]
+	}
+	if len(g.replay) == 0 {
+		g.ui.UpdateMsg(9, "replay finished")
+	}
+}
+
+func (g *Game) Apply(cmd Command) {
+	switch cmd.Kind {
+	case CMD_WAYPOINT:
+		g.HandleWayPointInput(cmd.Button, *cmd.Pos)
+	case CMD_OBSTACLE:
+		to := cmd.Pos
+		if cmd.To != nil {
+			to = cmd.To
+		}
+		g.HandleObstacleInput(cmd.Button, cmd.Brush, *cmd.Pos, *to)
+	case CMD_CHASER:
+		g.HandleChaserInput(cmd.Button, *cmd.Pos)
+	case CMD_FLOW:
+		g.HandleFlowInput(cmd.Button, *cmd.Pos)
+	case CMD_TOGGLE_MODE:
+		g.c.ToggleMode()
+		g.ui.UpdateMsg(3, MODENAMES[g.c.mode])
+	case CMD_TOGGLE_SEARCH:
+		g.c.ToggleSearch()
+		g.ui.UpdateMsg(7, SEARCHNAMES[g.c.search])
+	case CMD_TOGGLE_SMOOTH:
+		g.c.ToggleSmooth()
+		g.ui.UpdateMsg(12, SMOOTHNAMES[g.c.smooth])
+	case CMD_CLEAR_OBSTACLES:
+		g.w.ClearObstacles()
+	case CMD_RANDOM_OBSTACLES:
+		g.w.RandomObstacles()
+	case CMD_PAUSE:
+		g.paused = !g.paused
+	case CMD_LOAD_LEVEL:
+		l, err := ReadLevel(strings.NewReader(cmd.Level))
+		if err != nil {
+			g.ui.UpdateMsg(9, "bad level: "+err.Error())
+			return
+		}
+		if err := g.w.LoadLevel(l, g.c.search, g.c.smooth); err != nil {
+			g.ui.UpdateMsg(6, pathErrMsg(err))
+		}
+		g.ui.UpdateMsg(2, fmt.Sprintf("grid dimension: %dx%d",
+			g.w.dm.Width(), g.w.dm.Height()))
+	}
+}
+
+func (g *Game) SaveLevel() {
+	if err := g.w.Level().Save(g.levelPath); err != nil {
+		g.ui.UpdateMsg(9, "couldn't save level: "+err.Error())
+		return
+	}
+	g.ui.UpdateMsg(9, "saved level to "+g.levelPath)
+}
+
+func (g *Game) LoadLevel() {
+	// read and check the file here, so the command carries its text
+	l, err := LoadLevel(g.levelPath)
+	if err != nil {
+		g.ui.UpdateMsg(9, "couldn't load level: "+err.Error())
+		return
+	}
+	g.Do(Command{Kind: CMD_LOAD_LEVEL, Level: l.String()})
+	g.ui.UpdateMsg(9, "loaded level from "+g.levelPath)
+}
+
 func (g *Game) HandleKeyEvents(e sdl.Event) {
 	switch e.(type) {
 	case *sdl.KeyboardEvent:
 		ke := e.(*sdl.KeyboardEvent)
 		if ke.Type == sdl.KEYDOWN {
 			if ke.Keysym.Sym == sdl.K_m {
-				g.c.ToggleMode()
-				g.ui.UpdateMsg(3, MODENAMES[g.c.mode])
+				g.Do(Command{Kind: CMD_TOGGLE_MODE})
+			}
+			if ke.Keysym.Sym == sdl.K_a {
+				g.Do(Command{Kind: CMD_TOGGLE_SEARCH})
+			}
+			if ke.Keysym.Sym == sdl.K_h {
+				g.Do(Command{Kind: CMD_TOGGLE_SMOOTH})
 			}
 			if ke.Keysym.Sym == sdl.K_c {
-				g.w.ClearObstacles()
+				g.Do(Command{Kind: CMD_CLEAR_OBSTACLES})
 			}
 			if ke.Keysym.Sym == sdl.K_g {
-				g.w.RandomObstacles()
+				g.Do(Command{Kind: CMD_RANDOM_OBSTACLES})
+			}
+			if ke.Keysym.Sym == sdl.K_l {
+				g.w.dm.ToggleShownLayer()
+				g.ui.UpdateMsg(8, "showing layer: "+g.w.dm.ShownLayer())
 			}
 			if ke.Keysym.Sym == sdl.K_i {
 				g.showData = !g.showData
 			}
 			if ke.Keysym.Sym == sdl.K_p {
-				g.paused = !g.paused
+				g.Do(Command{Kind: CMD_PAUSE})
+			}
+			if ke.Keysym.Sym == sdl.K_b {
+				g.c.ToggleBrush()
+				g.ui.UpdateMsg(11, BRUSHNAMES[g.c.brush])
+			}
+			if ke.Keysym.Sym == sdl.K_s {
+				g.SaveLevel()
+			}
+			if ke.Keysym.Sym == sdl.K_o {
+				g.LoadLevel()
 			}
 		}
 	}
@@ -62,14 +242,18 @@ func (g *Game) HandleMouseMotionEvents(me *sdl.MouseMotionEvent) {
 	p := MouseMotionEventToVec2D(me)
 	if me.State&sdl.ButtonLMask() != 0 {
 		if g.c.mode == MODE_PLACING_WAYPOINT {
-			g.HandleWayPointInput(sdl.BUTTON_LEFT, p)
+			g.Do(Command{Kind: CMD_WAYPOINT, Button: sdl.BUTTON_LEFT, Pos: &p})
+		} else if g.c.mode == MODE_PLACING_OBSTACLE &&
+			g.c.brush == BRUSH_POINT {
+			g.Do(Command{Kind: CMD_OBSTACLE, Button: sdl.BUTTON_LEFT, Pos: &p})
 		}
 	}
 	if me.State&sdl.ButtonRMask() != 0 {
 		if g.c.mode == MODE_PLACING_WAYPOINT {
-			g.HandleWayPointInput(sdl.BUTTON_RIGHT, p)
-		} else if g.c.mode == MODE_PLACING_OBSTACLE {
-			g.HandleObstacleInput(sdl.BUTTON_LEFT, p)
+			g.Do(Command{Kind: CMD_WAYPOINT, Button: sdl.BUTTON_RIGHT, Pos: &p})
+		} else if g.c.mode == MODE_PLACING_OBSTACLE &&
+			g.c.brush == BRUSH_POINT {
+			g.Do(Command{Kind: CMD_OBSTACLE, Button: sdl.BUTTON_RIGHT, Pos: &p})
 		}
 	}
 }
@@ -78,84 +262,135 @@ func (g *Game) HandleMouseButtonEvents(me *sdl.MouseButtonEvent) {
 	p := MouseButtonEventToVec2D(me)
 	if me.Type == sdl.MOUSEBUTTONDOWN {
 		if g.c.mode == MODE_PLACING_WAYPOINT {
-			g.HandleWayPointInput(me.Button, p)
+			g.Do(Command{Kind: CMD_WAYPOINT, Button: me.Button, Pos: &p})
 		} else if g.c.mode == MODE_PLACING_OBSTACLE {
-			g.HandleObstacleInput(me.Button, p)
+			if g.c.brush == BRUSH_RECT || g.c.brush == BRUSH_LINE {
+				// applied when the button comes back up
+				g.strokeStart = &p
+				g.strokeButton = me.Button
+			} else {
+				g.Do(Command{Kind: CMD_OBSTACLE,
+					Button: me.Button, Brush: g.c.brush, Pos: &p})
+			}
 		} else if g.c.mode == MODE_PLACING_CHASER {
-			g.HandleChaserInput(me.Button, p)
+			g.Do(Command{Kind: CMD_CHASER, Button: me.Button, Pos: &p})
+		} else if g.c.mode == MODE_FLOW_FIELD {
+			g.Do(Command{Kind: CMD_FLOW, Button: me.Button, Pos: &p})
+		}
+	}
+	if me.Type == sdl.MOUSEBUTTONUP &&
+		g.strokeStart != nil && me.Button == g.strokeButton {
+		if g.c.mode == MODE_PLACING_OBSTACLE {
+			g.Do(Command{Kind: CMD_OBSTACLE, Button: me.Button,
+				Brush: g.c.brush, Pos: g.strokeStart, To: &p})
 		}
+		g.strokeStart = nil
 	}
 }
 
 func (g *Game) HandleChaserInput(button uint8, p Vec2D) {
-	pos := Position{
-		int(p.X / GRIDCELL_WORLD_W),
-		int(p.Y / GRIDCELL_WORLD_H),
-	}
+	pos := g.w.dm.CellOf(p)
 	if !g.w.dm.InGrid(pos.X, pos.Y) ||
 		g.w.dm.CellHasObstacle(pos.X, pos.Y) {
 		return
 	}
-	p = Vec2D{
-		float64(pos.X*GRIDCELL_WORLD_W + GRIDCELL_WORLD_W/2),
-		float64(pos.Y*GRIDCELL_WORLD_H + GRIDCELL_WORLD_H/2)}
+	p = g.w.dm.ToWorldSpace(pos)
 	if button == sdl.BUTTON_LEFT {
 		g.w.chasers = append(g.w.chasers, NewChaser(p, g.w))
 	}
+	if button == sdl.BUTTON_RIGHT {
+		g.w.AddScentSource(LAYER_FOOD, pos)
+	}
+	if button == sdl.BUTTON_MIDDLE {
+		g.w.AddScentSource(LAYER_DANGER, pos)
+	}
 }
 
-func (g *Game) HandleWayPointInput(button uint8, p Vec2D) {
-	pos := Position{
-		int(p.X / GRIDCELL_WORLD_W),
-		int(p.Y / GRIDCELL_WORLD_H),
-	}
+// the left button places a flow agent, the right button moves the goal
+// they all flow toward
+func (g *Game) HandleFlowInput(button uint8, p Vec2D) {
+	pos := g.w.dm.CellOf(p)
 	if !g.w.dm.InGrid(pos.X, pos.Y) ||
 		g.w.dm.CellHasObstacle(pos.X, pos.Y) {
 		return
 	}
-	p = Vec2D{
-		float64(pos.X*GRIDCELL_WORLD_W + GRIDCELL_WORLD_W/2),
-		float64(pos.Y*GRIDCELL_WORLD_H + GRIDCELL_WORLD_H/2)}
 	if button == sdl.BUTTON_LEFT {
+		g.w.flowAgents = append(g.w.flowAgents,
+			NewFlowAgent(g.w.dm.ToWorldSpace(pos), g.w))
+	}
+	if button == sdl.BUTTON_RIGHT {
+		t0 := time.Now()
+		g.w.SetFlowGoal(pos)
+		msg := fmt.Sprintf("flow field compute took %.3f ms",
+			float64(time.Since(t0).Microseconds())/1000.0)
+		g.ui.UpdateMsg(5, msg)
+	}
+}
+
+func (g *Game) HandleWayPointInput(button uint8, p Vec2D) {
+	pos := g.w.dm.CellOf(p)
+	inGrid := g.w.dm.InGrid(pos.X, pos.Y)
+	if inGrid {
+		p = g.w.dm.ToWorldSpace(pos)
+	}
+	if button == sdl.BUTTON_LEFT {
+		if !inGrid || g.w.dm.CellHasObstacle(pos.X, pos.Y) {
+			return
+		}
 		g.w.e = NewEntity(p, g.w)
 	}
 	if button == sdl.BUTTON_RIGHT {
 		if g.w.e != nil {
-			g.w.e.moveTarget = &p
-
-			startCell := g.w.dm.ToGridSpace(g.w.e.pos)
-			endCell := g.w.dm.ToGridSpace(*g.w.e.moveTarget)
+			g.startTrace()
 			t0 := time.Now()
-			path := g.w.pc.Path(startCell, endCell)
+			res, err := g.w.MoveEntityTo(p, g.c.search, g.c.smooth)
+			elapsed := time.Since(t0)
+			g.finishTrace()
 			msg := fmt.Sprintf("path compute took %.3f ms",
-				float64(time.Since(t0).Nanoseconds()/1e6)/1000.0)
+				float64(elapsed.Microseconds())/1000.0)
 			g.ui.UpdateMsg(5, msg)
-			g.ui.UpdateMsg(6, fmt.Sprintf("path length: %d", len(path)))
-
-			g.w.e.path = g.w.e.path[:0]
-			for _, p := range path {
-				g.w.e.path = append(g.w.e.path, Vec2D{
-					float64(p.X*GRIDCELL_WORLD_W + GRIDCELL_WORLD_W/2),
-					float64(p.Y*GRIDCELL_WORLD_H + GRIDCELL_WORLD_H/2),
-				})
+			if err != nil {
+				g.ui.UpdateMsg(6, pathErrMsg(err))
+				return
 			}
+			g.ui.UpdateMsg(6, fmt.Sprintf(
+				"path length: %d, cost: %d, nodes expanded: %d",
+				len(res.Path), res.Cost, res.Expanded))
 		}
 	}
 }
 
-func (g *Game) HandleObstacleInput(button uint8, pos Vec2D) {
+// a UI message for a failed path query
+func pathErrMsg(err error) string {
+	switch {
+	case errors.Is(err, gridpath.ErrOutOfBounds):
+		return "no path: target is off the grid"
+	case errors.Is(err, gridpath.ErrStartBlocked):
+		return "no path: entity is stuck in an obstacle"
+	case errors.Is(err, gridpath.ErrEndBlocked):
+		return "no path: target is an obstacle"
+	case errors.Is(err, gridpath.ErrUnreachable):
+		return "no path: target is unreachable"
+	}
+	return "no path: " + err.Error()
+}
+
+// the left button paints obstacles with the brush, the right button erases
+// them. from and to are the ends of the stroke (the same point for the
+// point and fill brushes)
+func (g *Game) HandleObstacleInput(
+	button uint8, brush BrushMode, from Vec2D, to Vec2D) {
+	a := g.w.dm.CellOf(from)
+	b := g.w.dm.CellOf(to)
+	if (brush == BRUSH_POINT || brush == BRUSH_FILL) &&
+		!g.w.dm.InGrid(a.X, a.Y) {
+		return
+	}
 	if button == sdl.BUTTON_LEFT {
-		pos := Position{
-			int(pos.X / GRIDCELL_WORLD_W),
-			int(pos.Y / GRIDCELL_WORLD_H),
-		}
-		if !g.w.dm.InGrid(pos.X, pos.Y) {
-			return
-		}
-		g.w.AddObstacle(pos)
+		g.w.Paint(brush, a, b, true)
 	}
 	if button == sdl.BUTTON_RIGHT {
-
+		g.w.Paint(brush, a, b, false)
 	}
 }
 
@@ -192,33 +427,20 @@ func (g *Game) HandleEvents() bool {
 
 func (g *Game) gameloop() int {
 
-	fpsTicker := time.NewTicker(time.Millisecond * (1000 / FPS))
-
 gameloop:
 	for {
-		// try to draw
-		select {
-		case _ = <-fpsTicker.C:
-			sdl.Do(func() {
-				g.r.Clear()
-				g.DrawGrid()
-				g.DrawUI()
-				g.r.Present()
-			})
-		default:
-		}
-
 		// handle input
 		if !g.HandleEvents() {
 			break gameloop
 		}
 
-		// update grid
-		if !g.paused {
-			g.w.Update()
-		}
+		// update grid and draw, as many ticks as are due
+		g.clock.Update()
 
-		sdl.Delay(1000 / (2 * FPS))
+		if wait := g.clock.UntilNext(); wait > 0 {
+			sdl.Delay(uint32(wait / time.Millisecond))
+		}
 	}
+	g.Close()
 	return 0
 }
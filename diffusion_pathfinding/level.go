@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A level is stored as text, e.g.
+//
+//	# comments start with '#'
+//	size 10 4
+//	waypoint 8 3
+//	waypoint 1 0
+//	map
+//	.....#....
+//	..@..#..c.
+//	.....#....
+//	..........
+//
+// size gives the grid width and height in cells and waypoints are listed in
+// the order the entity visits them. After the map line come height rows of
+// width cells, listed from the top of the screen (the highest y) down: '#'
+// is an obstacle, '@' the entity's spawn, 'c' a chaser's spawn and '.' an
+// empty cell
+type Level struct {
+	W, H      int
+	Obstacles []Position
+	// nil if the level has no entity
+	Entity    *Position
+	Chasers   []Position
+	Waypoints []Position
+}
+
+const (
+	LEVEL_EMPTY    = '.'
+	LEVEL_OBSTACLE = '#'
+	LEVEL_ENTITY   = '@'
+	LEVEL_CHASER   = 'c'
+)
+
+func LoadLevel(path string) (*Level, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLevel(f)
+}
+
+func ReadLevel(r io.Reader) (*Level, error) {
+	l := &Level{}
+	sc := bufio.NewScanner(r)
+	line := 0
+	// the header, up to the map line
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if text == "map" {
+			break
+		}
+		var p Position
+		switch fields := strings.Fields(text); fields[0] {
+		case "size":
+			if _, err := fmt.Sscanf(text, "size %d %d", &l.W, &l.H); err != nil {
+				return nil, fmt.Errorf("level line %d: %v", line, err)
+			}
+			if l.W <= 0 || l.H <= 0 {
+				return nil, fmt.Errorf("level line %d: bad size %dx%d",
+					line, l.W, l.H)
+			}
+		case "waypoint":
+			if _, err := fmt.Sscanf(text, "waypoint %d %d", &p.X, &p.Y); err != nil {
+				return nil, fmt.Errorf("level line %d: %v", line, err)
+			}
+			l.Waypoints = append(l.Waypoints, p)
+		default:
+			return nil, fmt.Errorf("level line %d: unknown directive %q",
+				line, fields[0])
+		}
+	}
+	if l.W == 0 {
+		return nil, fmt.Errorf("level has no size")
+	}
+	// the map rows, top of the screen first
+	for row := 0; row < l.H; row++ {
+		if !sc.Scan() {
+			return nil, fmt.Errorf("level has %d map rows, expected %d",
+				row, l.H)
+		}
+		line++
+		text := strings.TrimRight(sc.Text(), " \t\r")
+		if len(text) != l.W {
+			return nil, fmt.Errorf("level line %d: row is %d cells, expected %d",
+				line, len(text), l.W)
+		}
+		y := l.H - 1 - row
+		for x, c := range []byte(text) {
+			p := Position{X: x, Y: y}
+			switch c {
+			case LEVEL_EMPTY:
+			case LEVEL_OBSTACLE:
+				l.Obstacles = append(l.Obstacles, p)
+			case LEVEL_ENTITY:
+				if l.Entity != nil {
+					return nil, fmt.Errorf("level line %d: second entity", line)
+				}
+				l.Entity = &p
+			case LEVEL_CHASER:
+				l.Chasers = append(l.Chasers, p)
+			default:
+				return nil, fmt.Errorf("level line %d: unknown cell %q",
+					line, c)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range l.Waypoints {
+		if p.X < 0 || p.X >= l.W || p.Y < 0 || p.Y >= l.H {
+			return nil, fmt.Errorf("waypoint %v is off the map", p)
+		}
+	}
+	return l, nil
+}
+
+func (l *Level) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := l.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *Level) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "size %d %d\n", l.W, l.H)
+	for _, p := range l.Waypoints {
+		fmt.Fprintf(bw, "waypoint %d %d\n", p.X, p.Y)
+	}
+	fmt.Fprintln(bw, "map")
+	for _, row := range l.Rows() {
+		fmt.Fprintln(bw, row)
+	}
+	return bw.Flush()
+}
+
+// the map section of the level, top of the screen first
+func (l *Level) Rows() []string {
+	cells := make([][]byte, l.H)
+	for y := range cells {
+		cells[y] = []byte(strings.Repeat(string(LEVEL_EMPTY), l.W))
+	}
+	set := func(p Position, c byte) {
+		if p.X >= 0 && p.X < l.W && p.Y >= 0 && p.Y < l.H {
+			cells[l.H-1-p.Y][p.X] = c
+		}
+	}
+	for _, p := range l.Obstacles {
+		set(p, LEVEL_OBSTACLE)
+	}
+	for _, p := range l.Chasers {
+		set(p, LEVEL_CHASER)
+	}
+	if l.Entity != nil {
+		set(*l.Entity, LEVEL_ENTITY)
+	}
+	rows := make([]string, l.H)
+	for i, r := range cells {
+		rows[i] = string(r)
+	}
+	return rows
+}
+
+func (l *Level) String() string {
+	var b strings.Builder
+	l.Write(&b)
+	return b.String()
+}
+
+// a Scenario starting from the level, running for ticks (the entity spawns
+// at 0, 0 if the level has none)
+func (l *Level) Scenario(ticks int) Scenario {
+	s := Scenario{
+		W:         l.W,
+		H:         l.H,
+		CellSize:  GRIDCELL_WORLD_W,
+		Obstacles: l.Obstacles,
+		Chasers:   l.Chasers,
+		Waypoints: l.Waypoints,
+		Ticks:     ticks,
+	}
+	if l.Entity != nil {
+		s.EntitySpawn = *l.Entity
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadFixtures(t *testing.T) map[string]*Level {
+	paths, err := filepath.Glob("levels/*.txt")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no level fixtures found (%v)", err)
+	}
+	levels := make(map[string]*Level)
+	for _, path := range paths {
+		l, err := LoadLevel(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		levels[path] = l
+	}
+	return levels
+}
+
+func TestLevelRoundTrip(t *testing.T) {
+	for path, l := range loadFixtures(t) {
+		l2, err := ReadLevel(strings.NewReader(l.String()))
+		if err != nil {
+			t.Fatalf("%s: re-reading written level: %v", path, err)
+		}
+		if l2.String() != l.String() {
+			t.Fatalf("%s: level changed on round trip:\n%s\nvs\n%s",
+				path, l, l2)
+		}
+	}
+}
+
+func TestLevelErrors(t *testing.T) {
+	for _, text := range []string{
+		"map\n",
+		"size 3 2\nmap\n...\n",
+		"size 3 2\nmap\n...\n..\n",
+		"size 3 2\nmap\n...\n.x.\n",
+		"size 3 2\nmap\n@..\n..@\n",
+		"size 3 2\nwaypoint 3 0\nmap\n...\n...\n",
+		"size 3 2\nteleporter 1 1\nmap\n...\n...\n",
+		"size -3 2\nmap\n",
+	} {
+		if _, err := ReadLevel(strings.NewReader(text)); err == nil {
+			t.Fatalf("expected an error for level:\n%s", text)
+		}
+	}
+}
+
+func TestLevelRowsTopDown(t *testing.T) {
+	l, err := ReadLevel(strings.NewReader("size 3 2\nwaypoint 2 0\nmap\n#..\n.@c\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Obstacles) != 1 || l.Obstacles[0] != (Position{X: 0, Y: 1}) {
+		t.Fatalf("expected an obstacle at 0, 1, got %v", l.Obstacles)
+	}
+	if *l.Entity != (Position{X: 1, Y: 0}) ||
+		l.Chasers[0] != (Position{X: 2, Y: 0}) {
+		t.Fatalf("entity at %v, chaser at %v", *l.Entity, l.Chasers[0])
+	}
+}
+
+func TestLevelWorldRoundTrip(t *testing.T) {
+	for path, l := range loadFixtures(t) {
+		w := NewHeadlessWorld(1, 1, GRIDCELL_WORLD_W, 0)
+		w.LoadLevel(l, SEARCH_ASTAR, SMOOTH_NONE)
+		saved := w.Level()
+		saved.Waypoints = l.Waypoints
+		if saved.String() != l.String() {
+			t.Fatalf("%s: world didn't save the level it loaded:\n%s\nvs\n%s",
+				path, l, saved)
+		}
+	}
+}
+
+// a level whose size doesn't divide the world still fills it
+func TestLoadLevelFillsWorld(t *testing.T) {
+	l, err := ReadLevel(strings.NewReader(
+		"size 24 2\nmap\n" + strings.Repeat(".", 24) + "\n" +
+			strings.Repeat(".", 24) + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewHeadlessWorld(1, 1, GRIDCELL_WORLD_W, 0)
+	w.LoadLevel(l, SEARCH_ASTAR, SMOOTH_NONE)
+	if size := w.dm.CellSize() * 24; size != GRID_WORLD_DIMENSION {
+		t.Fatalf("24 cells span %f, want %d", size, GRID_WORLD_DIMENSION)
+	}
+}
+
+// A* and JPS should find equally short paths between successive waypoints
+// of every fixture
+func TestLevelPaths(t *testing.T) {
+	for path, l := range loadFixtures(t) {
+		if l.Entity == nil {
+			continue
+		}
+		w := NewHeadlessWorld(l.W, l.H, GRIDCELL_WORLD_W, 0)
+		for _, o := range l.Obstacles {
+			w.AddObstacle(o)
+		}
+		from := *l.Entity
+		for _, to := range l.Waypoints {
+			astar := w.pc.Path(from, to)
+			jps := w.pc.JPSPath(from, to)
+			if len(astar) == 0 || len(jps) == 0 {
+				t.Fatalf("%s: no path from %v to %v", path, from, to)
+			}
+			if pathLength(astar) != pathLength(jps) {
+				t.Fatalf("%s: A* path from %v to %v costs %d, JPS %d",
+					path, from, to, pathLength(astar), pathLength(jps))
+			}
+			from = to
+		}
+	}
+}
+
+func pathLength(path []Position) int {
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		cost += gridpath.OctileDistance(path[i-1], path[i])
+	}
+	return cost
+}
+
+func TestLevelScenarios(t *testing.T) {
+	for path, l := range loadFixtures(t) {
+		s := l.Scenario(6000)
+		res := s.Run()
+		if res.WaypointsReached != len(l.Waypoints) {
+			t.Fatalf("%s: entity reached %d of %d waypoints",
+				path, res.WaypointsReached, len(l.Waypoints))
+		}
+		if res.Caught() != len(l.Chasers) {
+			t.Fatalf("%s: %d of %d chasers caught the entity",
+				path, res.Caught(), len(l.Chasers))
+		}
+	}
+}
@@ -12,9 +12,12 @@ import (
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "if provided, use as filename of prof output")
+var record = flag.String("record", "", "if provided, record the session's commands to this file")
+var level = flag.String("level", "level.txt", "file the s and o keys save and load the level to and from")
+var trace = flag.String("trace", "", "if provided, write a JSON trace and PNG heatmap of each path search, named with this prefix")
+var replay = flag.String("replay", "", "if provided, replay the commands recorded in this file")
 
 func init() {
-	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 }
 
@@ -38,6 +41,8 @@ func InitSDL() (*sdl.Renderer, *ttf.Font) {
 }
 
 func main() {
+	// parsed here rather than in init() so that `go test` flags don't trip it
+	flag.Parse()
 
 	var exitcode int
 	sdl.Main(func() {
@@ -51,8 +56,27 @@ func main() {
 			}
 			defer pprof.StopCPUProfile()
 		}
+		seed := time.Now().UnixNano()
+		var cmds []Command
+		if *replay != "" {
+			var err error
+			seed, cmds, err = LoadReplay(*replay)
+			if err != nil {
+				log.Fatal("could not load replay: ", err)
+			}
+		}
 		r, f := InitSDL()
-		g := NewGame(r, f)
+		g := NewGame(r, f, seed)
+		g.levelPath = *level
+		g.tracePrefix = *trace
+		if *record != "" {
+			if err := g.StartRecording(*record, seed); err != nil {
+				log.Fatal("could not start recording: ", err)
+			}
+		}
+		if *replay != "" {
+			g.StartReplay(cmds)
+		}
 		exitcode = g.gameloop()
 	})
 	os.Exit(exitcode)
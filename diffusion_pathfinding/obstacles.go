@@ -0,0 +1,59 @@
+package main
+
+// the set of obstacle cells, in the order they were added (so iterating
+// over it is deterministic), with O(1) lookup and removal
+type ObstacleSet struct {
+	cells []Position
+	// index of each cell in cells
+	ix map[Position]int
+}
+
+func NewObstacleSet() *ObstacleSet {
+	return &ObstacleSet{ix: make(map[Position]int)}
+}
+
+func (s *ObstacleSet) Has(p Position) bool {
+	_, ok := s.ix[p]
+	return ok
+}
+
+// add p, returning false if it was already there
+func (s *ObstacleSet) Add(p Position) bool {
+	if s.Has(p) {
+		return false
+	}
+	s.ix[p] = len(s.cells)
+	s.cells = append(s.cells, p)
+	return true
+}
+
+// remove p, returning false if it wasn't there. The last cell takes p's
+// place in the order
+func (s *ObstacleSet) Remove(p Position) bool {
+	i, ok := s.ix[p]
+	if !ok {
+		return false
+	}
+	last := len(s.cells) - 1
+	s.cells[i] = s.cells[last]
+	s.ix[s.cells[i]] = i
+	s.cells = s.cells[:last]
+	delete(s.ix, p)
+	return true
+}
+
+func (s *ObstacleSet) Clear() {
+	s.cells = s.cells[:0]
+	for p := range s.ix {
+		delete(s.ix, p)
+	}
+}
+
+func (s *ObstacleSet) Len() int {
+	return len(s.cells)
+}
+
+// the cells in the set (not to be modified)
+func (s *ObstacleSet) Cells() []Position {
+	return s.cells
+}
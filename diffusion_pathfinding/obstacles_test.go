@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+)
+
+// the world's obstacle set and its DiffusionMap's obstacle grid should
+// always agree
+func checkObstaclesConsistent(t *testing.T, w *World) {
+	t.Helper()
+	n := 0
+	for y := 0; y < w.dm.Height(); y++ {
+		for x := 0; x < w.dm.Width(); x++ {
+			p := Position{X: x, Y: y}
+			if w.dm.CellHasObstacle(x, y) != w.obstacles.Has(p) {
+				t.Fatalf("obstacle grid and set disagree at %v", p)
+			}
+			if w.obstacles.Has(p) {
+				n++
+			}
+		}
+	}
+	if n != w.obstacles.Len() || n != len(w.obstacles.Cells()) {
+		t.Fatalf("%d obstacles on the grid, %d in the set", n, w.obstacles.Len())
+	}
+}
+
+func TestObstacleSet(t *testing.T) {
+	s := NewObstacleSet()
+	for _, p := range []Position{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 3}} {
+		if !s.Add(p) {
+			t.Fatalf("adding %v reported a duplicate", p)
+		}
+	}
+	if s.Add(Position{X: 2, Y: 2}) || s.Len() != 3 {
+		t.Fatal("duplicate was added")
+	}
+	if !s.Remove(Position{X: 1, Y: 1}) || s.Remove(Position{X: 1, Y: 1}) {
+		t.Fatal("remove reported the wrong result")
+	}
+	if s.Has(Position{X: 1, Y: 1}) || !s.Has(Position{X: 3, Y: 3}) ||
+		s.Len() != 2 {
+		t.Fatalf("wrong contents after remove: %v", s.Cells())
+	}
+	for _, p := range s.Cells() {
+		if s.Cells()[s.ix[p]] != p {
+			t.Fatalf("index of %v is stale", p)
+		}
+	}
+}
+
+func TestBrushCells(t *testing.T) {
+	rect := rectCells(Position{X: 3, Y: 1}, Position{X: 1, Y: 2})
+	if len(rect) != 6 {
+		t.Fatalf("expected a 3x2 rect, got %v", rect)
+	}
+	line := lineCells(Position{X: 0, Y: 0}, Position{X: 6, Y: 3})
+	if len(line) != 7 || line[0] != (Position{X: 0, Y: 0}) ||
+		line[6] != (Position{X: 6, Y: 3}) {
+		t.Fatalf("unexpected line %v", line)
+	}
+	for i := 1; i < len(line); i++ {
+		dx, dy := line[i].X-line[i-1].X, line[i].Y-line[i-1].Y
+		if dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+			t.Fatalf("line has a gap between %v and %v", line[i-1], line[i])
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	w := NewHeadlessWorld(10, 10, GRIDCELL_WORLD_W, 0)
+
+	// a closed 6x6 ring (a box with its inside erased), around the middle of
+	// the grid
+	w.Paint(BRUSH_RECT, Position{X: 2, Y: 2}, Position{X: 7, Y: 7}, true)
+	w.Paint(BRUSH_RECT, Position{X: 3, Y: 3}, Position{X: 6, Y: 6}, false)
+	checkObstaclesConsistent(t, w)
+	if w.obstacles.Len() != 36-16 {
+		t.Fatalf("expected a 20 cell ring, got %d obstacles", w.obstacles.Len())
+	}
+	w.Paint(BRUSH_POINT, Position{X: 2, Y: 2}, Position{X: 2, Y: 2}, true)
+	if w.obstacles.Len() != 20 {
+		t.Fatal("painting over an obstacle duplicated it")
+	}
+
+	// filling inside the closed box shouldn't leak out
+	w.Paint(BRUSH_FILL, Position{X: 4, Y: 4}, Position{X: 4, Y: 4}, true)
+	checkObstaclesConsistent(t, w)
+	if w.obstacles.Len() != 36 {
+		t.Fatalf("expected a solid 6x6 block, got %d obstacles", w.obstacles.Len())
+	}
+
+	// erasing the block with a fill clears exactly it
+	w.Paint(BRUSH_FILL, Position{X: 7, Y: 2}, Position{X: 7, Y: 2}, false)
+	checkObstaclesConsistent(t, w)
+	if w.obstacles.Len() != 0 {
+		t.Fatalf("%d obstacles left after erasing", w.obstacles.Len())
+	}
+
+	// lines and rects hanging off the grid are clipped to it
+	w.Paint(BRUSH_LINE, Position{X: -5, Y: 5}, Position{X: 15, Y: 5}, true)
+	w.Paint(BRUSH_RECT, Position{X: 8, Y: -3}, Position{X: 20, Y: 1}, true)
+	checkObstaclesConsistent(t, w)
+	if w.obstacles.Len() != 10+4 {
+		t.Fatalf("unexpected cells after clipped strokes: %v",
+			w.obstacles.Cells())
+	}
+
+	// the entity's cell is never painted over
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 0, Y: 9}), w)
+	w.Paint(BRUSH_FILL, Position{X: 0, Y: 8}, Position{X: 0, Y: 8}, true)
+	checkObstaclesConsistent(t, w)
+	if w.obstacles.Has(Position{X: 0, Y: 9}) {
+		t.Fatal("fill buried the entity")
+	}
+
+	w.ClearObstacles()
+	checkObstaclesConsistent(t, w)
+}
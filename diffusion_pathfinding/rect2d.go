@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/veandco/go-sdl2/sdl"
+	"math"
 )
 
 type Rect2D struct {
@@ -33,6 +34,15 @@ func (r1 Rect2D) Overlaps(r2 Rect2D) bool {
 		r1.Y+r1.H < r2.Y)
 }
 
+// the smallest rect containing both
+func (r1 Rect2D) Union(r2 Rect2D) Rect2D {
+	x0 := math.Min(r1.X, r2.X)
+	y0 := math.Min(r1.Y, r2.Y)
+	x1 := math.Max(r1.X+r1.W, r2.X+r2.W)
+	y1 := math.Max(r1.Y+r1.H, r2.Y+r2.H)
+	return Rect2D{x0, y0, x1 - x0, y1 - y0}
+}
+
 func (r Rect2D) Add(v Vec2D) Rect2D {
 	return Rect2D{r.X + v.X, r.Y + v.Y, r.W, r.H}
 }
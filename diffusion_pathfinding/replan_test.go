@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+// the cells the entity's remaining path passes through
+func pathCells(w *World) map[Position]bool {
+	cells := make(map[Position]bool)
+	for _, p := range w.e.path {
+		cells[w.dm.ToGridSpace(p)] = true
+	}
+	return cells
+}
+
+func TestEntityReplansAroundNewWall(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 3, Y: 15}), w)
+	goal := w.dm.ToWorldSpace(Position{X: 26, Y: 15})
+	w.MoveEntityTo(goal, SEARCH_DSTAR_LITE, SMOOTH_NONE)
+	for i := 0; i < 50; i++ {
+		w.UpdateAgents()
+	}
+	// a wall across the straight path, painted while the entity walks
+	w.Paint(BRUSH_LINE, Position{X: 15, Y: 5}, Position{X: 15, Y: 25}, true)
+	if !pathCells(w)[Position{X: 15, Y: 15}] {
+		t.Fatal("path changed before the next tick")
+	}
+	w.UpdateAgents()
+	cells := pathCells(w)
+	for o := range cells {
+		if w.dm.CellHasObstacle(o.X, o.Y) {
+			t.Fatalf("replanned path goes through the wall at %v", o)
+		}
+	}
+	if len(cells) == 0 {
+		t.Fatal("no path after replanning")
+	}
+	for i := 0; i < 3000; i++ {
+		w.UpdateAgents()
+		if w.e.pos.Sub(goal).Magnitude() < w.dm.cellSize/4 {
+			return
+		}
+	}
+	t.Fatalf("entity never reached the goal, ended at %v", w.e.pos)
+}
+
+// walling the goal off mid-walk stops the entity instead of sending it
+// straight at the wall
+func TestEntityStopsWhenGoalSealed(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 3, Y: 15}), w)
+	w.MoveEntityTo(w.dm.ToWorldSpace(Position{X: 26, Y: 15}),
+		SEARCH_DSTAR_LITE, SMOOTH_NONE)
+	for i := 0; i < 20; i++ {
+		w.UpdateAgents()
+	}
+	w.Paint(BRUSH_RECT, Position{X: 24, Y: 13}, Position{X: 28, Y: 17}, true)
+	w.Paint(BRUSH_RECT, Position{X: 25, Y: 14}, Position{X: 27, Y: 16}, false)
+	w.UpdateAgents()
+	if w.e.moveTarget != nil || len(w.e.path) != 0 {
+		t.Fatalf("entity still heading for the sealed goal (%d path points)",
+			len(w.e.path))
+	}
+	stopped := w.e.pos
+	for i := 0; i < 100; i++ {
+		w.UpdateAgents()
+	}
+	if w.e.pos != stopped {
+		t.Fatalf("entity moved from %v to %v after stopping", stopped, w.e.pos)
+	}
+}
+
+func TestEntityReplansWhenObstaclesCleared(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	w.Paint(BRUSH_LINE, Position{X: 15, Y: 0}, Position{X: 15, Y: 25}, true)
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 3, Y: 15}), w)
+	w.MoveEntityTo(w.dm.ToWorldSpace(Position{X: 26, Y: 15}),
+		SEARCH_DSTAR_LITE, SMOOTH_NONE)
+	around := len(w.e.path)
+	w.ClearObstacles()
+	w.UpdateAgents()
+	if len(w.e.path) >= around {
+		t.Fatalf("path didn't shorten once the wall was cleared: %d -> %d",
+			around, len(w.e.path))
+	}
+}
+
+func TestOtherSearchesDontReplan(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 3, Y: 15}), w)
+	w.MoveEntityTo(w.dm.ToWorldSpace(Position{X: 26, Y: 15}),
+		SEARCH_ASTAR, SMOOTH_NONE)
+	before := len(w.e.path)
+	w.Paint(BRUSH_LINE, Position{X: 15, Y: 5}, Position{X: 15, Y: 25}, true)
+	w.UpdateAgents()
+	if len(w.e.path) > before {
+		t.Fatal("A* path was replanned")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+type CommandKind string
+
+// the game-level commands a session is made of
+const (
+	CMD_WAYPOINT         CommandKind = "waypoint"
+	CMD_OBSTACLE         CommandKind = "obstacle"
+	CMD_CHASER           CommandKind = "chaser"
+	CMD_TOGGLE_MODE      CommandKind = "toggle_mode"
+	CMD_TOGGLE_SEARCH    CommandKind = "toggle_search"
+	CMD_TOGGLE_SMOOTH    CommandKind = "toggle_smooth"
+	CMD_CLEAR_OBSTACLES  CommandKind = "clear_obstacles"
+	CMD_RANDOM_OBSTACLES CommandKind = "random_obstacles"
+	CMD_PAUSE            CommandKind = "pause"
+	CMD_LOAD_LEVEL       CommandKind = "load_level"
+	CMD_FLOW             CommandKind = "flow"
+)
+
+// a game-level command, applied before the world updates on tick Tick.
+// Button and Pos (world space) are only used by the placement commands,
+// Brush and To (the end of a rect or line stroke) by CMD_OBSTACLE, and
+// Level (the level file's text, so the replay doesn't depend on the file)
+// by CMD_LOAD_LEVEL
+type Command struct {
+	Tick   int         `json:"tick"`
+	Kind   CommandKind `json:"kind"`
+	Button uint8       `json:"button,omitempty"`
+	Pos    *Vec2D      `json:"pos,omitempty"`
+	Brush  BrushMode   `json:"brush,omitempty"`
+	To     *Vec2D      `json:"to,omitempty"`
+	Level  string      `json:"level,omitempty"`
+}
+
+// the first line of a recording: the seed of the World's rng, which
+// RandomObstacles draws from
+type replayHeader struct {
+	Seed int64 `json:"seed"`
+}
+
+// writes a session to a file as JSON lines: a replayHeader followed by one
+// Command per line. Each command is written as soon as it's recorded, so
+// the file is usable even if the game crashes
+type Recorder struct {
+	f   *os.File
+	enc *json.Encoder
+}
+
+func NewRecorder(path string, seed int64) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &Recorder{f: f, enc: json.NewEncoder(f)}
+	if err := rec.enc.Encode(replayHeader{Seed: seed}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (rec *Recorder) Record(cmd Command) error {
+	return rec.enc.Encode(cmd)
+}
+
+func (rec *Recorder) Close() error {
+	return rec.f.Close()
+}
+
+// read back a file written by a Recorder
+func LoadReplay(path string) (seed int64, cmds []Command, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
+
+func ReadReplay(r io.Reader) (seed int64, cmds []Command, err error) {
+	dec := json.NewDecoder(r)
+	var h replayHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, nil, err
+	}
+	for {
+		var cmd Command
+		err := dec.Decode(&cmd)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return h.Seed, cmds, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	rec, err := NewRecorder(path, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := []Command{
+		{Tick: 0, Kind: CMD_WAYPOINT, Button: 1, Pos: &Vec2D{100, 200}},
+		{Tick: 0, Kind: CMD_TOGGLE_MODE},
+		{Tick: 17, Kind: CMD_OBSTACLE, Button: 1, Pos: &Vec2D{48.5, 12}},
+		{Tick: 90, Kind: CMD_RANDOM_OBSTACLES},
+	}
+	for _, cmd := range cmds {
+		if err := rec.Record(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec.Close()
+
+	seed, loaded, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed != 1234 {
+		t.Fatalf("expected seed 1234, got %d", seed)
+	}
+	if len(loaded) != len(cmds) {
+		t.Fatalf("expected %d commands, got %d", len(cmds), len(loaded))
+	}
+	for i, cmd := range cmds {
+		l := loaded[i]
+		if l.Tick != cmd.Tick || l.Kind != cmd.Kind || l.Button != cmd.Button ||
+			(l.Pos == nil) != (cmd.Pos == nil) ||
+			(l.Pos != nil && *l.Pos != *cmd.Pos) {
+			t.Fatalf("command %d: expected %+v, got %+v", i, cmd, l)
+		}
+	}
+}
+
+func TestLoadReplayErrors(t *testing.T) {
+	if _, _, err := LoadReplay(filepath.Join(t.TempDir(), "nope")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+	path := filepath.Join(t.TempDir(), "bad.jsonl")
+	os.WriteFile(path, []byte("{\"seed\": 1}\n{\"tick\": \n"), 0644)
+	if _, _, err := LoadReplay(path); err == nil {
+		t.Fatal("expected an error for a truncated command")
+	}
+}
+
+func TestRandomObstaclesSeeded(t *testing.T) {
+	a := NewHeadlessWorld(42, 42, GRIDCELL_WORLD_W, 99)
+	b := NewHeadlessWorld(42, 42, GRIDCELL_WORLD_W, 99)
+	for i := 0; i < 3; i++ {
+		a.RandomObstacles()
+		b.RandomObstacles()
+	}
+	if a.obstacles.Len() != b.obstacles.Len() {
+		t.Fatalf("%d vs %d obstacles", a.obstacles.Len(), b.obstacles.Len())
+	}
+	for i, o := range a.obstacles.Cells() {
+		if o != b.obstacles.Cells()[i] {
+			t.Fatalf("obstacle %d differs: %v vs %v",
+				i, o, b.obstacles.Cells()[i])
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+// names of the layers used by the sketch (any name can be used)
+const (
+	LAYER_PLAYER = "player"
+	LAYER_FOOD   = "food"
+	LAYER_DANGER = "danger"
+)
+
+// the fraction of its neighbors' average a cell keeps each sweep
+const DEFAULT_ATTENUATION = 0.998
+
+// a source whose strength decays below this is removed from its layer
+const MIN_SOURCE_STRENGTH = 0.001
+
+// how much a chaser cares about each layer (negative to flee)
+type ScentWeights map[string]float64
+
+var DEFAULT_SCENT_WEIGHTS = ScentWeights{
+	LAYER_PLAYER: 1.0,
+	LAYER_FOOD:   0.5,
+	LAYER_DANGER: -1.0,
+}
+
+// a cell pinned to Strength while diffusing. Strength is multiplied by
+// Decay after every Diffuse, so 1.0 is a permanent source and e.g. 0.99
+// is a scent that fades out over a few seconds
+type DiffusionSource struct {
+	Pos      Position
+	Strength float64
+	Decay    float64
+}
+
+// a named diffusion field with its own set of sources
+type ScentLayer struct {
+	Name string
+	// the fraction of its neighbors' average a cell keeps each sweep
+	Attenuation float64
+
+	m *DiffusionMap
+	d []float64
+	// back buffer written by each diffusion step, then swapped with d
+	next    []float64
+	sources []*DiffusionSource
+	// strength each source cell is pinned to during the current sweep,
+	// by cell index
+	pinned map[int]float64
+	// pinned as of the previous sweep
+	prevPinned map[int]float64
+	// whether each cell is in pinned, by cell index
+	isPinned []bool
+	// the DiffusionMap's obstacleGen as of the last time this layer was
+	// diffused
+	obstacleGen int
+	// the largest change to any cell in the last step
+	residual float64
+	// whether residual was within tolerance
+	settled bool
+}
+
+func NewScentLayer(m *DiffusionMap, name string) *ScentLayer {
+	return &ScentLayer{
+		Name:        name,
+		Attenuation: DEFAULT_ATTENUATION,
+		m:           m,
+		d:           make([]float64, m.w*m.h),
+		next:        make([]float64, m.w*m.h),
+		pinned:      make(map[int]float64),
+		prevPinned:  make(map[int]float64),
+		isPinned:    make([]bool, m.w*m.h),
+	}
+}
+
+// the value of the layer's field at x, y
+func (l *ScentLayer) At(x int, y int) float64 {
+	return l.d[l.m.ix(x, y)]
+}
+
+func (l *ScentLayer) Sources() []*DiffusionSource {
+	return l.sources
+}
+
+func (l *ScentLayer) AddSource(
+	pos Position, strength float64, decay float64) *DiffusionSource {
+	s := &DiffusionSource{Pos: pos, Strength: strength, Decay: decay}
+	l.sources = append(l.sources, s)
+	return s
+}
+
+func (l *ScentLayer) RemoveSource(s *DiffusionSource) {
+	for i, o := range l.sources {
+		if o == s {
+			l.sources = append(l.sources[:i], l.sources[i+1:]...)
+			return
+		}
+	}
+}
+
+// whether a source of this layer sits at x, y
+func (l *ScentLayer) IsSource(x int, y int) bool {
+	for _, s := range l.sources {
+		if s.Pos.X == x && s.Pos.Y == y {
+			return true
+		}
+	}
+	return false
+}
+
+// pin the source cells (the strongest source wins if two share a cell)
+// and decay the sources, dropping those which have faded out. Returns
+// whether the pinned cells differ from the previous call
+func (l *ScentLayer) pinSources() bool {
+	l.pinned, l.prevPinned = l.prevPinned, l.pinned
+	for i := range l.pinned {
+		delete(l.pinned, i)
+	}
+	for i := range l.prevPinned {
+		l.isPinned[i] = false
+	}
+	live := l.sources[:0]
+	for _, s := range l.sources {
+		if l.m.InGrid(s.Pos.X, s.Pos.Y) {
+			i := l.m.ix(s.Pos.X, s.Pos.Y)
+			if v, ok := l.pinned[i]; !ok || s.Strength > v {
+				l.pinned[i] = s.Strength
+			}
+			l.isPinned[i] = true
+		}
+		s.Strength *= s.Decay
+		if s.Strength >= MIN_SOURCE_STRENGTH {
+			live = append(live, s)
+		}
+	}
+	for i := len(live); i < len(l.sources); i++ {
+		l.sources[i] = nil
+	}
+	l.sources = live
+
+	if len(l.pinned) != len(l.prevPinned) {
+		return true
+	}
+	for i, v := range l.pinned {
+		if pv, ok := l.prevPinned[i]; !ok || pv != v {
+			return true
+		}
+	}
+	return false
+}
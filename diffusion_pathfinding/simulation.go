@@ -0,0 +1,148 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/simclock"
+)
+
+// a chaser counts as having caught the entity within this distance
+const CATCH_DISTANCE = (ENTITYSZ + POINTSZ) / 2
+
+// a scripted run of a headless World: the entity spawns at EntitySpawn and
+// walks to each of Waypoints in turn (pathing with Search, smoothed by
+// Smooth), while chasers spawned at Chasers follow the diffusion field
+type Scenario struct {
+	// grid dimensions in cells, and the world-space size of a cell
+	W, H     int
+	CellSize float64
+
+	Obstacles   []Position
+	EntitySpawn Position
+	Waypoints   []Position
+	Chasers     []Position
+	Search      SearchMode
+	Smooth      SmoothMode
+	// seeds the World's rng
+	Seed int64
+	// how chasers steer (if nil, DEFAULT_STEERING_WEIGHTS)
+	Steering *SteeringWeights
+
+	// how many ticks to run for. The run ends early once every chaser has
+	// caught the entity and the entity has reached every waypoint
+	Ticks int
+	// run the diffusion every this many ticks (if 0, every DIFFUSION_PERIOD
+	// as in the game)
+	DiffuseEvery int
+}
+
+type ChaserResult struct {
+	// world-space position of the chaser at each tick
+	Trajectory []Vec2D
+	// the tick on which the chaser caught the entity, or -1
+	CaughtAt int
+}
+
+type SimResult struct {
+	// ticks actually run
+	Ticks int
+	// world-space position of the entity at each tick
+	Entity  []Vec2D
+	Chasers []ChaserResult
+	// how many waypoints the entity reached
+	WaypointsReached int
+	// why the entity couldn't path to the waypoint after the last one it
+	// reached (nil if it could)
+	PathErr error
+}
+
+// the number of chasers which caught the entity
+func (r *SimResult) Caught() int {
+	n := 0
+	for _, c := range r.Chasers {
+		if c.CaughtAt >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// build the scenario's World without any renderer
+func (s *Scenario) World() *World {
+	w := NewHeadlessWorld(s.W, s.H, s.CellSize, s.Seed)
+	if s.Steering != nil {
+		w.steering = *s.Steering
+	}
+	for _, o := range s.Obstacles {
+		w.AddObstacle(o)
+	}
+	w.e = NewEntity(w.dm.ToWorldSpace(s.EntitySpawn), w)
+	for _, c := range s.Chasers {
+		w.chasers = append(w.chasers, NewChaser(w.dm.ToWorldSpace(c), w))
+	}
+	return w
+}
+
+// step the scenario's World tick by tick on a SIM_DT clock, as fast as
+// possible, recording what happens
+func (s *Scenario) Run() SimResult {
+	w := s.World()
+	clock := simclock.New(SIM_DT, &simclock.ManualTime{})
+	clock.Every(1, func(int) { w.UpdateAgents() })
+	if s.DiffuseEvery > 0 {
+		clock.Every(s.DiffuseEvery, func(int) { w.UpdateDiffusion() })
+	} else {
+		clock.EveryDuration(DIFFUSION_PERIOD,
+			func(int) { w.UpdateDiffusion() })
+	}
+
+	res := SimResult{Chasers: make([]ChaserResult, len(w.chasers))}
+	for i := range res.Chasers {
+		res.Chasers[i].CaughtAt = -1
+	}
+
+	waypoint := 0
+	// head for waypoint i, giving up on the rest if there's no path
+	moveTo := func(i int) {
+		_, err := w.MoveEntityTo(
+			w.dm.ToWorldSpace(s.Waypoints[i]), s.Search, s.Smooth)
+		if err != nil {
+			res.PathErr = err
+			waypoint = len(s.Waypoints)
+		}
+	}
+	if len(s.Waypoints) > 0 {
+		moveTo(0)
+	}
+
+	for t := 0; t < s.Ticks; t++ {
+		clock.Step()
+		res.Ticks++
+
+		res.Entity = append(res.Entity, w.e.pos)
+		caught := 0
+		for i, c := range w.chasers {
+			cr := &res.Chasers[i]
+			cr.Trajectory = append(cr.Trajectory, c.pos)
+			if cr.CaughtAt < 0 &&
+				c.pos.Sub(w.e.pos).Magnitude() < CATCH_DISTANCE {
+				cr.CaughtAt = t
+			}
+			if cr.CaughtAt >= 0 {
+				caught++
+			}
+		}
+		if len(w.chasers) > 0 && caught == len(w.chasers) &&
+			waypoint == len(s.Waypoints) {
+			break
+		}
+
+		if waypoint < len(s.Waypoints) &&
+			w.e.pos.Sub(*w.e.moveTarget).Magnitude() < w.dm.cellSize/4 {
+			waypoint++
+			res.WaypointsReached++
+			if waypoint < len(s.Waypoints) {
+				moveTo(waypoint)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"testing"
+)
+
+func wall(x int, y0 int, y1 int) []Position {
+	var ps []Position
+	for y := y0; y <= y1; y++ {
+		ps = append(ps, Position{X: x, Y: y})
+	}
+	return ps
+}
+
+func TestSimulationChasersCatchStillEntity(t *testing.T) {
+	s := Scenario{
+		W: 30, H: 30, CellSize: GRIDCELL_WORLD_W,
+		EntitySpawn: Position{X: 25, Y: 15},
+		Chasers:     []Position{{X: 3, Y: 3}, {X: 3, Y: 27}},
+		Ticks:       3000,
+	}
+	res := s.Run()
+	if res.Caught() != len(s.Chasers) {
+		t.Fatalf("only %d of %d chasers caught the entity in %d ticks",
+			res.Caught(), len(s.Chasers), res.Ticks)
+	}
+	for i, c := range res.Chasers {
+		if len(c.Trajectory) != res.Ticks {
+			t.Fatalf("chaser %d has %d trajectory points for %d ticks",
+				i, len(c.Trajectory), res.Ticks)
+		}
+	}
+}
+
+func TestSimulationChaserGoesAroundWall(t *testing.T) {
+	s := Scenario{
+		W: 30, H: 30, CellSize: GRIDCELL_WORLD_W,
+		Obstacles:   wall(15, 0, 24),
+		EntitySpawn: Position{X: 25, Y: 5},
+		Chasers:     []Position{{X: 5, Y: 5}},
+		Ticks:       6000,
+	}
+	res := s.Run()
+	c := res.Chasers[0]
+	if c.CaughtAt < 0 {
+		t.Fatalf("chaser didn't get around the wall, ended at %v",
+			c.Trajectory[len(c.Trajectory)-1])
+	}
+	// the only way through is below the end of the wall
+	below := false
+	for _, p := range c.Trajectory {
+		if p.Y > 24*GRIDCELL_WORLD_W {
+			below = true
+		}
+	}
+	if !below {
+		t.Fatal("chaser got past the wall without going around it")
+	}
+}
+
+func TestSimulationWaypoints(t *testing.T) {
+	s := Scenario{
+		W: 30, H: 30, CellSize: GRIDCELL_WORLD_W,
+		Obstacles:   wall(15, 5, 29),
+		EntitySpawn: Position{X: 5, Y: 25},
+		Waypoints:   []Position{{X: 25, Y: 25}, {X: 25, Y: 5}},
+		Ticks:       3000,
+	}
+	res := s.Run()
+	if res.WaypointsReached != len(s.Waypoints) {
+		t.Fatalf("entity reached %d of %d waypoints, ended at %v",
+			res.WaypointsReached, len(s.Waypoints), res.Entity[len(res.Entity)-1])
+	}
+}
+
+func TestSimulationDeterministic(t *testing.T) {
+	s := Scenario{
+		W: 30, H: 30, CellSize: GRIDCELL_WORLD_W,
+		Obstacles:   wall(12, 3, 20),
+		EntitySpawn: Position{X: 20, Y: 10},
+		Waypoints:   []Position{{X: 20, Y: 25}, {X: 5, Y: 25}},
+		Chasers:     []Position{{X: 2, Y: 2}, {X: 28, Y: 2}},
+		Search:      SEARCH_JPS,
+		Ticks:       1000,
+	}
+	a, b := s.Run(), s.Run()
+	if a.Ticks != b.Ticks {
+		t.Fatalf("runs lasted %d and %d ticks", a.Ticks, b.Ticks)
+	}
+	for i := range a.Chasers {
+		for j := range a.Chasers[i].Trajectory {
+			if a.Chasers[i].Trajectory[j] != b.Chasers[i].Trajectory[j] {
+				t.Fatalf("chaser %d diverged at tick %d", i, j)
+			}
+		}
+	}
+}
+
+func TestSimulationEverySearchReachesWaypoints(t *testing.T) {
+	l, err := LoadLevel("levels/maze.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for search := SearchMode(0); search < N_SEARCHES; search++ {
+		s := l.Scenario(6000)
+		s.Chasers = nil
+		s.Search = search
+		res := s.Run()
+		if res.WaypointsReached != len(s.Waypoints) {
+			t.Fatalf("%s: entity reached %d of %d waypoints, ended at %v",
+				SEARCHNAMES[search], res.WaypointsReached, len(s.Waypoints),
+				res.Entity[len(res.Entity)-1])
+		}
+	}
+}
+
+func TestSimulationUnreachableWaypoint(t *testing.T) {
+	// the second waypoint is boxed in; the entity stops after the first
+	box := []Position{
+		{X: 20, Y: 19}, {X: 20, Y: 21}, {X: 19, Y: 20}, {X: 21, Y: 20},
+		{X: 19, Y: 19}, {X: 21, Y: 21}, {X: 19, Y: 21}, {X: 21, Y: 19}}
+	waypoints := []Position{{X: 10, Y: 5}, {X: 20, Y: 20}, {X: 25, Y: 5}}
+	for _, search := range []SearchMode{
+		SEARCH_ASTAR, SEARCH_JPS, SEARCH_THETA, SEARCH_DSTAR_LITE} {
+		s := Scenario{
+			W: 30, H: 30, CellSize: GRIDCELL_WORLD_W,
+			Obstacles:   box,
+			EntitySpawn: Position{X: 5, Y: 5},
+			Waypoints:   waypoints,
+			Search:      search,
+			Ticks:       3000,
+		}
+		res := s.Run()
+		if res.WaypointsReached != 1 {
+			t.Fatalf("%s: reached %d waypoints, want 1",
+				SEARCHNAMES[search], res.WaypointsReached)
+		}
+		if !errors.Is(res.PathErr, gridpath.ErrUnreachable) {
+			t.Fatalf("%s: got error %v, want unreachable",
+				SEARCHNAMES[search], res.PathErr)
+		}
+	}
+}
+
+func TestMoveEntityToErrors(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	w.AddObstacle(Position{X: 10, Y: 10})
+	w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 5, Y: 5}), w)
+	cases := []struct {
+		to   Vec2D
+		want error
+	}{
+		{w.dm.ToWorldSpace(Position{X: 40, Y: 5}), gridpath.ErrOutOfBounds},
+		{w.dm.ToWorldSpace(Position{X: 10, Y: 10}), gridpath.ErrEndBlocked},
+	}
+	for _, c := range cases {
+		for search := SearchMode(0); search < N_SEARCHES; search++ {
+			_, err := w.MoveEntityTo(c.to, search, SMOOTH_NONE)
+			if !errors.Is(err, c.want) {
+				t.Fatalf("%s to %v: got %v, want %v",
+					SEARCHNAMES[search], c.to, err, c.want)
+			}
+			if w.e.moveTarget != nil || len(w.e.path) != 0 {
+				t.Fatalf("%s: entity still moving after %v",
+					SEARCHNAMES[search], err)
+			}
+		}
+	}
+	res, err := w.MoveEntityTo(
+		w.dm.ToWorldSpace(Position{X: 20, Y: 5}), SEARCH_ASTAR, SMOOTH_NONE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Path[0] != (Position{X: 5, Y: 5}) || res.Cost != 150 ||
+		res.Expanded == 0 {
+		t.Fatalf("got %+v", res)
+	}
+	if w.e.path[0] != w.dm.ToWorldSpace(Position{X: 5, Y: 5}) {
+		t.Fatalf("entity's path starts at %v", w.e.path[0])
+	}
+}
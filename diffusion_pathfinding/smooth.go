@@ -0,0 +1,104 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"math"
+)
+
+// how the entity's path is post-processed after the search
+type SmoothMode int
+
+const N_SMOOTHS = 3
+
+const (
+	// walk every cell of the path
+	SMOOTH_NONE = 0
+	// drop the waypoints which can be skipped by walking straight
+	SMOOTH_STRING_PULL = iota
+	// string-pull, then curve through the remaining waypoints
+	SMOOTH_CATMULL_ROM = iota
+)
+
+var SMOOTHNAMES []string = []string{
+	"SMOOTH_NONE",
+	"SMOOTH_STRING_PULL",
+	"SMOOTH_CATMULL_ROM",
+}
+
+// the world-space waypoints for the entity to follow along path (cells in
+// either order), smoothed according to smooth
+func (m *DiffusionMap) SmoothPath(
+	path []Position, smooth SmoothMode) []Vec2D {
+	if smooth != SMOOTH_NONE {
+		path = gridpath.StringPull(m, path)
+	}
+	pts := make([]Vec2D, len(path))
+	for i, p := range path {
+		pts[i] = m.ToWorldSpace(p)
+	}
+	if smooth == SMOOTH_CATMULL_ROM {
+		pts = CatmullRom(pts, m.cellSize/2, m.clearOfObstacles)
+	}
+	return pts
+}
+
+// whether p is off the grid or in a cell without an obstacle
+func (m *DiffusionMap) clearOfObstacles(p Vec2D) bool {
+	c := m.CellOf(p)
+	return !m.InGrid(c.X, c.Y) || !m.CellHasObstacle(c.X, c.Y)
+}
+
+// a uniform Catmull-Rom spline through pts, sampled about every spacing
+// units along each segment. The spline passes through every point of pts
+// (the first and last are repeated to give the end segments their
+// tangents). A segment any of whose samples fails ok is left straight
+func CatmullRom(pts []Vec2D, spacing float64, ok func(Vec2D) bool) []Vec2D {
+	if len(pts) < 3 {
+		return append([]Vec2D(nil), pts...)
+	}
+	var out []Vec2D
+	var seg []Vec2D
+	for i := 0; i+1 < len(pts); i++ {
+		p0, p1, p2, p3 := pts[i], pts[i], pts[i+1], pts[i+1]
+		if i > 0 {
+			p0 = pts[i-1]
+		}
+		if i+2 < len(pts) {
+			p3 = pts[i+2]
+		}
+		n := int(math.Ceil(p2.Sub(p1).Magnitude() / spacing))
+		if n < 1 {
+			n = 1
+		}
+		seg = seg[:0]
+		straight := false
+		for k := 0; k < n; k++ {
+			p := catmullRomPoint(p0, p1, p2, p3, float64(k)/float64(n))
+			if ok != nil && !ok(p) {
+				straight = true
+				break
+			}
+			seg = append(seg, p)
+		}
+		if straight {
+			out = append(out, p1)
+		} else {
+			out = append(out, seg...)
+		}
+	}
+	return append(out, pts[len(pts)-1])
+}
+
+// the point t of the way from p1 to p2 along a uniform Catmull-Rom spline
+func catmullRomPoint(p0, p1, p2, p3 Vec2D, t float64) Vec2D {
+	t2 := t * t
+	t3 := t2 * t
+	f := func(a, b, c, d float64) float64 {
+		return 0.5 * (2*b + (-a+c)*t +
+			(2*a-5*b+4*c-d)*t2 +
+			(-a+3*b-3*c+d)*t3)
+	}
+	return Vec2D{
+		f(p0.X, p1.X, p2.X, p3.X),
+		f(p0.Y, p1.Y, p2.Y, p3.Y)}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCatmullRomPassesThroughPoints(t *testing.T) {
+	pts := []Vec2D{{0, 0}, {100, 0}, {100, 100}, {200, 150}}
+	curve := CatmullRom(pts, 10, nil)
+	for _, p := range pts {
+		found := false
+		for _, c := range curve {
+			if c.Sub(p).Magnitude() < 1e-9 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("curve misses control point %v", p)
+		}
+	}
+	for i := 0; i+1 < len(curve); i++ {
+		if d := curve[i+1].Sub(curve[i]).Magnitude(); d > 20 {
+			t.Fatalf("samples %v and %v are %.1f apart", curve[i], curve[i+1], d)
+		}
+	}
+}
+
+func TestSmoothPathOpen(t *testing.T) {
+	w := NewHeadlessWorld(20, 20, GRIDCELL_WORLD_W, 1)
+	path := w.pc.Path(Position{X: 1, Y: 1}, Position{X: 18, Y: 7})
+	if got := w.dm.SmoothPath(path, SMOOTH_NONE); len(got) != len(path) {
+		t.Fatalf("SMOOTH_NONE changed the path: %d -> %d points",
+			len(path), len(got))
+	}
+	pulled := w.dm.SmoothPath(path, SMOOTH_STRING_PULL)
+	if len(pulled) != 2 {
+		t.Fatalf("open path pulled to %d points, want 2", len(pulled))
+	}
+}
+
+func TestSmoothPathAvoidsObstacles(t *testing.T) {
+	l, err := LoadLevel("levels/maze.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewHeadlessWorld(l.W, l.H, GRIDCELL_WORLD_W, 1)
+	for _, o := range l.Obstacles {
+		w.AddObstacle(o)
+	}
+	path := w.pc.Path(*l.Entity, l.Waypoints[0])
+	if len(path) == 0 {
+		t.Fatal("no path through the maze")
+	}
+	for _, smooth := range []SmoothMode{SMOOTH_STRING_PULL, SMOOTH_CATMULL_ROM} {
+		pts := w.dm.SmoothPath(path, smooth)
+		if smooth == SMOOTH_STRING_PULL && len(pts) >= len(path) {
+			t.Fatalf("%s: %d points from %d cells",
+				SMOOTHNAMES[smooth], len(pts), len(path))
+		}
+		for _, p := range pts {
+			if !w.dm.clearOfObstacles(p) {
+				t.Fatalf("%s: waypoint %v is in an obstacle",
+					SMOOTHNAMES[smooth], p)
+			}
+		}
+	}
+}
+
+// the distance the entity covered over a run
+func trajectoryLength(ps []Vec2D) float64 {
+	d := 0.0
+	for i := 0; i+1 < len(ps); i++ {
+		d += ps[i+1].Sub(ps[i]).Magnitude()
+	}
+	return d
+}
+
+func TestSmoothedEntityWalksShorter(t *testing.T) {
+	l, err := LoadLevel("levels/maze.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lengths []float64
+	for _, smooth := range []SmoothMode{SMOOTH_NONE, SMOOTH_STRING_PULL, SMOOTH_CATMULL_ROM} {
+		s := l.Scenario(6000)
+		s.Chasers = nil
+		s.Smooth = smooth
+		res := s.Run()
+		if res.WaypointsReached != len(s.Waypoints) {
+			t.Fatalf("%s: reached %d of %d waypoints",
+				SMOOTHNAMES[smooth], res.WaypointsReached, len(s.Waypoints))
+		}
+		lengths = append(lengths, trajectoryLength(res.Entity))
+	}
+	if lengths[1] >= lengths[0] || lengths[2] >= lengths[0] {
+		t.Fatalf("smoothing didn't shorten the walk: %v", lengths)
+	}
+}
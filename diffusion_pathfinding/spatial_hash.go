@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+)
+
+// buckets chasers by position so that finding a chaser's neighbours only
+// looks at the buckets around it rather than at every chaser. Rebuilt from
+// scratch each tick, since everything moves
+type SpatialHash struct {
+	// world-space width (and height) of a bucket
+	size    float64
+	buckets map[Position][]*Chaser
+}
+
+func NewSpatialHash(size float64) *SpatialHash {
+	return &SpatialHash{size: size, buckets: make(map[Position][]*Chaser)}
+}
+
+func (h *SpatialHash) bucket(p Vec2D) Position {
+	return Position{
+		X: int(math.Floor(p.X / h.size)),
+		Y: int(math.Floor(p.Y / h.size))}
+}
+
+// empty the hash, keeping the buckets' storage
+func (h *SpatialHash) Clear() {
+	for k, b := range h.buckets {
+		h.buckets[k] = b[:0]
+	}
+}
+
+func (h *SpatialHash) Insert(c *Chaser) {
+	k := h.bucket(c.pos)
+	h.buckets[k] = append(h.buckets[k], c)
+}
+
+// append to buf the chasers within r of p (including any at p itself), in
+// a deterministic order
+func (h *SpatialHash) Near(p Vec2D, r float64, buf []*Chaser) []*Chaser {
+	lo := h.bucket(Vec2D{p.X - r, p.Y - r})
+	hi := h.bucket(Vec2D{p.X + r, p.Y + r})
+	for y := lo.Y; y <= hi.Y; y++ {
+		for x := lo.X; x <= hi.X; x++ {
+			for _, c := range h.buckets[Position{X: x, Y: y}] {
+				if c.pos.Sub(p).Magnitude() <= r {
+					buf = append(buf, c)
+				}
+			}
+		}
+	}
+	return buf
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+)
+
+// chasers push apart within this distance of each other
+const SEPARATION_RADIUS = POINTSZ * 2
+
+// chasers match the velocity of others within this distance
+const ALIGNMENT_RADIUS = POINTSZ * 4
+
+// chasers are pushed away from obstacles within this distance
+const AVOIDANCE_RADIUS = POINTSZ * 2
+
+// how much each steering force contributes to a chaser's steer vector.
+//
+// Seek:		toward the next cell up the scent gradient
+// Separation:	away from chasers within SEPARATION_RADIUS
+// Alignment:	toward the average velocity of chasers within ALIGNMENT_RADIUS
+// Avoidance:	away from obstacles within AVOIDANCE_RADIUS
+type SteeringWeights struct {
+	Seek       float64
+	Separation float64
+	Alignment  float64
+	Avoidance  float64
+}
+
+var DEFAULT_STEERING_WEIGHTS = SteeringWeights{
+	Seek:       1.0,
+	Separation: 1.5,
+	Alignment:  0.3,
+	Avoidance:  0.5,
+}
+
+// only seeking, as chasers steered before they knew about each other
+var SEEK_ONLY_STEERING = SteeringWeights{Seek: 1.0}
+
+// the push away from chasers within SEPARATION_RADIUS, stronger the closer
+// they are. Chasers sitting exactly on top of each other are split apart
+// along x, in the order they're listed in the world
+func (c *Chaser) separation(neighbors []*Chaser) Vec2D {
+	var f Vec2D
+	for _, n := range neighbors {
+		if n == c {
+			continue
+		}
+		away := c.pos.Sub(n.pos)
+		d := away.Magnitude()
+		if d > SEPARATION_RADIUS {
+			continue
+		}
+		if d == 0 {
+			away = Vec2D{1, 0}
+			if c.ix < n.ix {
+				away = Vec2D{-1, 0}
+			}
+		} else {
+			away = away.Unit()
+		}
+		f = f.Add(away.Scale(1 - d/SEPARATION_RADIUS))
+	}
+	return f
+}
+
+// the difference between the average velocity of chasers within
+// ALIGNMENT_RADIUS and this chaser's own
+func (c *Chaser) alignment(neighbors []*Chaser) Vec2D {
+	var sum Vec2D
+	n := 0
+	for _, o := range neighbors {
+		if o == c {
+			continue
+		}
+		sum = sum.Add(o.vel)
+		n++
+	}
+	if n == 0 {
+		return Vec2D{0, 0}
+	}
+	return sum.Scale(1 / float64(n)).Sub(c.vel).Truncate(1)
+}
+
+// the push away from obstacles within AVOIDANCE_RADIUS of the chaser's
+// edge, stronger the closer they are
+func (c *Chaser) avoidance() Vec2D {
+	var f Vec2D
+	r := float64(POINTSZ/2 + AVOIDANCE_RADIUS)
+	x0, y0, x1, y1 := c.w.dm.CellRange(CenteredSquare(c.pos, 2*r))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if !c.w.dm.CellHasObstacle(x, y) {
+				continue
+			}
+			orec := c.w.dm.CellRect(Position{X: x, Y: y})
+			// the closest point of the obstacle to the chaser
+			closest := Vec2D{
+				math.Max(orec.X, math.Min(c.pos.X, orec.X+orec.W)),
+				math.Max(orec.Y, math.Min(c.pos.Y, orec.Y+orec.H))}
+			away := c.pos.Sub(closest)
+			d := away.Magnitude() - POINTSZ/2
+			if d >= AVOIDANCE_RADIUS || away.Magnitude() == 0 {
+				continue
+			}
+			if d < 0 {
+				d = 0
+			}
+			f = f.Add(away.Unit().Scale(1 - d/AVOIDANCE_RADIUS))
+		}
+	}
+	return f
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomChasers(w *World, n int, rng *rand.Rand) {
+	size := w.dm.CellSize() * float64(w.dm.Width())
+	for i := 0; i < n; i++ {
+		w.chasers = append(w.chasers, NewChaser(
+			Vec2D{rng.Float64() * size, rng.Float64() * size}, w))
+	}
+}
+
+func TestSpatialHashNear(t *testing.T) {
+	w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+	rng := rand.New(rand.NewSource(1))
+	randomChasers(w, 400, rng)
+	h := NewSpatialHash(ALIGNMENT_RADIUS)
+	for _, c := range w.chasers {
+		h.Insert(c)
+	}
+	for _, c := range w.chasers {
+		for _, r := range []float64{SEPARATION_RADIUS, ALIGNMENT_RADIUS, 3 * ALIGNMENT_RADIUS} {
+			near := make(map[*Chaser]bool)
+			for _, n := range h.Near(c.pos, r, nil) {
+				if near[n] {
+					t.Fatal("chaser returned twice")
+				}
+				near[n] = true
+			}
+			for _, o := range w.chasers {
+				if (o.pos.Sub(c.pos).Magnitude() <= r) != near[o] {
+					t.Fatalf("hash disagrees with brute force about %v near %v (r %v)",
+						o.pos, c.pos, r)
+				}
+			}
+		}
+	}
+}
+
+// the closest any two chasers get to each other
+func minSpacing(w *World) float64 {
+	min := -1.0
+	for i, a := range w.chasers {
+		for _, b := range w.chasers[i+1:] {
+			if d := a.pos.Sub(b.pos).Magnitude(); min < 0 || d < min {
+				min = d
+			}
+		}
+	}
+	return min
+}
+
+func TestSeparationSpreadsStackedChasers(t *testing.T) {
+	for _, sep := range []bool{true, false} {
+		w := NewHeadlessWorld(30, 30, GRIDCELL_WORLD_W, 1)
+		if !sep {
+			w.steering = SEEK_ONLY_STEERING
+		}
+		w.e = NewEntity(w.dm.ToWorldSpace(Position{X: 25, Y: 15}), w)
+		for i := 0; i < 5; i++ {
+			w.chasers = append(w.chasers,
+				NewChaser(w.dm.ToWorldSpace(Position{X: 5, Y: 15}), w))
+		}
+		for i := 0; i < 500; i++ {
+			w.UpdateAgents()
+			if i%2 == 0 {
+				w.UpdateDiffusion()
+			}
+		}
+		spacing := minSpacing(w)
+		if sep && spacing < POINTSZ/2 {
+			t.Fatalf("chasers still overlap with separation: %.2f apart", spacing)
+		}
+		if !sep && spacing != 0 {
+			t.Fatalf("seek-only chasers came apart (%.2f) with nothing to separate them",
+				spacing)
+		}
+	}
+}
+
+func TestAvoidancePushesAwayFromWalls(t *testing.T) {
+	w := NewHeadlessWorld(10, 10, 20, 1)
+	for y := 0; y < 10; y++ {
+		w.AddObstacle(Position{X: 5, Y: y})
+	}
+	c := NewChaser(Vec2D{100 - POINTSZ, 50}, w)
+	if f := c.avoidance(); f.X >= 0 || math.Abs(f.Y) > 1e-9 {
+		t.Fatalf("expected a push left, away from the wall, got %v", f)
+	}
+	c.pos = Vec2D{30, 50}
+	if f := c.avoidance(); f.Magnitude() != 0 {
+		t.Fatalf("pushed by a wall far away: %v", f)
+	}
+}
+
+func BenchmarkUpdateChasers(b *testing.B) {
+	for _, n := range []int{50, 200, 800} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			w := NewHeadlessWorld(64, 64, GRIDCELL_WORLD_W, 1)
+			randomChasers(w, n, rand.New(rand.NewSource(1)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w.UpdateAgents()
+			}
+		})
+	}
+}
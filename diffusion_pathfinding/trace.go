@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"os"
+)
+
+// pixels per cell in the trace heatmaps
+const TRACE_CELL_PX = 8
+
+// if tracing, attach a fresh Trace to the World's PathComputer, to record
+// the next search
+func (g *Game) startTrace() {
+	if g.tracePrefix == "" {
+		return
+	}
+	g.w.pc.Trace = gridpath.NewTrace(g.w.dm.Width(), g.w.dm.Height())
+}
+
+// write out what the last search recorded, as <tracePrefix>-<n>.json and
+// <tracePrefix>-<n>.png for the nth search traced. Searches which don't
+// run on the PathComputer (SEARCH_DSTAR_LITE) leave nothing to write
+func (g *Game) finishTrace() {
+	t := g.w.pc.Trace
+	g.w.pc.Trace = nil
+	if t == nil || len(t.Steps) == 0 {
+		return
+	}
+	name := fmt.Sprintf("%s-%d", g.tracePrefix, g.traces)
+	g.traces++
+	if err := writeTrace(t, name); err != nil {
+		g.ui.UpdateMsg(9, "couldn't write trace: "+err.Error())
+		return
+	}
+	g.ui.UpdateMsg(9, fmt.Sprintf("wrote trace %s (%d steps)",
+		name, len(t.Steps)))
+}
+
+func writeTrace(t *gridpath.Trace, name string) error {
+	f, err := os.Create(name + ".json")
+	if err != nil {
+		return err
+	}
+	if err := t.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	f, err = os.Create(name + ".png")
+	if err != nil {
+		return err
+	}
+	if err := t.WritePNG(f, TRACE_CELL_PX); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -2,68 +2,320 @@ package main
 
 import (
 	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"github.com/dt-rush/gamedev-sketchbook/simclock"
+	"github.com/veandco/go-sdl2/sdl"
 	"math/rand"
 	"time"
 )
 
 type World struct {
-	g         *Game
-	e         *Entity
-	chasers   []*Chaser
-	obstacles []Position
+	// nil when running headless
+	g       *Game
+	e       *Entity
+	chasers []*Chaser
+	// buckets chasers for neighbour lookups, rebuilt every tick
+	chaserHash *SpatialHash
+	// scratch slice for chaserHash lookups
+	nearBuf []*Chaser
+	// steering weights given to new chasers
+	steering SteeringWeights
+	// agents steering by flow, toward flow's goal
+	flowAgents []*FlowAgent
+	// kept in step with dm's obstacle grid by AddObstacle, RemoveObstacle
+	// and ClearObstacles
+	obstacles *ObstacleSet
 
 	dm *DiffusionMap
-	pc *PathComputer
+	pc *gridpath.PathComputer
+	// plans the entity's path under SEARCH_DSTAR_LITE, kept up to date with
+	// every obstacle change
+	planner *gridpath.DStarLite
+	// whether obstacles have changed since the entity's path was last
+	// planned
+	replan bool
+	// shared by every FlowAgent, recomputed when the obstacles change
+	flow *FlowField
+	// the source of the player layer, which follows e
+	playerSource *DiffusionSource
+
+	// source of randomness for RandomObstacles, seeded so that sessions
+	// can be replayed
+	rng *rand.Rand
 
 	param int
 }
 
-func NewWorld(g *Game) *World {
-	w := World{g: g}
+func NewWorld(g *Game, seed int64) *World {
+	w := newWorld(g, g.r,
+		GRID_CELL_DIMENSION, GRID_CELL_DIMENSION, GRIDCELL_WORLD_W, seed)
+	return w
+}
+
+// a world with no Game or renderer behind it, for running simulations
+func NewHeadlessWorld(
+	width int, height int, cellSize float64, seed int64) *World {
+	return newWorld(nil, nil, width, height, cellSize, seed)
+}
+
+func newWorld(g *Game, r *sdl.Renderer,
+	width int, height int, cellSize float64, seed int64) *World {
+	w := World{
+		g:          g,
+		obstacles:  NewObstacleSet(),
+		chaserHash: NewSpatialHash(ALIGNMENT_RADIUS),
+		steering:   DEFAULT_STEERING_WEIGHTS,
+	}
+	w.rng = rand.New(rand.NewSource(seed))
 	w.param = 0
-	w.dm = NewDiffusionMap(g.r, &w.obstacles, 16*time.Millisecond)
-	w.pc = NewPathComputer(w.dm)
+	w.dm = NewDiffusionMap(r, width, height, cellSize, nil)
+	w.pc = gridpath.NewPathComputer(w.dm, w.dm.Width(), w.dm.Height())
+	w.planner = gridpath.NewDStarLite(w.dm, w.dm.Width(), w.dm.Height())
+	w.flow = NewFlowField(w.dm)
 
 	return &w
 }
 
-func (w *World) Update() {
+// show a message in the UI, if there is one
+func (w *World) msg(i int, msg string) {
+	if w.g != nil {
+		w.g.ui.UpdateMsg(i, msg)
+	}
+}
+
+// run the world off c: agents move every tick and the diffusion runs
+// every DIFFUSION_PERIOD. Neither runs while paused() is true
+func (w *World) Schedule(c *simclock.Clock, paused func() bool) {
+	c.Every(1, func(int) {
+		if !paused() {
+			w.UpdateAgents()
+		}
+	})
+	c.EveryDuration(DIFFUSION_PERIOD, func(int) {
+		if !paused() {
+			w.UpdateDiffusion()
+		}
+	})
+}
+
+// move the entity, chasers and flow agents one step
+func (w *World) UpdateAgents() {
+	if w.replan {
+		w.replan = false
+		w.ReplanEntity()
+	}
 	if w.e != nil {
 		w.e.Update()
-		for _, c := range w.chasers {
-			c.Update()
+		if w.playerSource == nil {
+			w.playerSource = w.dm.AddSource(
+				LAYER_PLAYER, w.dm.ToGridSpace(w.e.pos), 1.0, 1.0)
+		}
+		w.playerSource.Pos = w.dm.ToGridSpace(w.e.pos)
+	}
+	// chasers are hashed where they were at the start of the tick; none
+	// moves far enough in a tick for that to matter
+	w.chaserHash.Clear()
+	for i, c := range w.chasers {
+		c.ix = i
+		w.chaserHash.Insert(c)
+	}
+	for _, c := range w.chasers {
+		c.Update()
+	}
+	if len(w.flowAgents) > 0 {
+		w.flow.Update()
+		for _, a := range w.flowAgents {
+			a.Update()
 		}
 	}
-	select {
-	case _ = <-w.dm.tick.C:
-		if w.e != nil {
-			t0 := time.Now()
-			w.dm.Diffuse(w.e.pos)
-			msg := fmt.Sprintf("diffusion took %.1f ms",
-				float64(time.Since(t0).Nanoseconds()/1e6)/1000.0)
-			w.g.ui.UpdateMsg(4, msg)
+}
+
+func (w *World) UpdateDiffusion() {
+	t0 := time.Now()
+	res := w.dm.Diffuse(DIFFUSION_MAX_ITERATIONS, DIFFUSION_TOLERANCE)
+	if !res.Skipped {
+		msg := fmt.Sprintf(
+			"diffusion took %.1f ms (%d iterations, residual %.1e)",
+			float64(time.Since(t0).Microseconds())/1000.0,
+			res.Iterations, res.Residual)
+		w.msg(4, msg)
+	}
+}
+
+// send the entity toward p, computing its path with the given search and
+// smoothing it as given. Returns the search's result (the path as searched,
+// before smoothing). If there's no path, the entity stops where it is and
+// the error says why (see gridpath.PathError)
+func (w *World) MoveEntityTo(p Vec2D, search SearchMode,
+	smooth SmoothMode) (gridpath.PathResult, error) {
+	startCell := w.dm.ToGridSpace(w.e.pos)
+	endCell := w.dm.CellOf(p)
+	var res gridpath.PathResult
+	var err error
+	if search == SEARCH_DSTAR_LITE {
+		err = gridpath.CheckEndpoints(w.dm, startCell, endCell)
+		if err == nil {
+			w.planner.Reset(startCell, endCell)
+			w.planner.Compute()
+			res, err = w.planner.Find()
 		}
-	default:
+	} else if search == SEARCH_JPS {
+		res, err = w.pc.Find(startCell, endCell, gridpath.JPS)
+	} else if search == SEARCH_THETA {
+		res, err = w.pc.Find(startCell, endCell, gridpath.THETA)
+	} else {
+		res, err = w.pc.Find(startCell, endCell, gridpath.ASTAR)
 	}
+	if err != nil {
+		w.stopEntity()
+		return res, err
+	}
+
+	w.e.moveTarget = &p
+	w.e.search = search
+	w.e.smooth = smooth
+	w.e.path = append(w.e.path[:0], w.dm.SmoothPath(res.Path, smooth)...)
+	return res, nil
 }
 
+// send the flow agents toward goal
+func (w *World) SetFlowGoal(goal Position) {
+	w.flow.SetGoal(goal)
+	for _, a := range w.flowAgents {
+		a.moveTarget = nil
+	}
+}
+
+// repair the entity's path after obstacles have changed, if it's following
+// a SEARCH_DSTAR_LITE path (paths from the other searches are left alone)
+func (w *World) ReplanEntity() {
+	if w.e == nil || w.e.moveTarget == nil ||
+		w.e.search != SEARCH_DSTAR_LITE {
+		return
+	}
+	w.planner.MoveStart(w.dm.ToGridSpace(w.e.pos))
+	w.planner.Compute()
+	// if the goal has been walled off, the entity stops where it is, as if
+	// MoveEntityTo had found no path
+	res, err := w.planner.Find()
+	if err != nil {
+		w.stopEntity()
+		w.msg(6, pathErrMsg(err))
+		return
+	}
+	w.e.path = append(w.e.path[:0], w.dm.SmoothPath(res.Path, w.e.smooth)...)
+}
+
+// the entity gives up on its move target and stands still
+func (w *World) stopEntity() {
+	w.e.moveTarget = nil
+	w.e.path = w.e.path[:0]
+	w.e.vel = Vec2D{}
+}
+
+// tell the planner o has changed; the entity's path is repaired on the
+// next UpdateAgents, so a whole brush stroke is repaired at once
+func (w *World) obstacleChanged(o Position) {
+	w.planner.UpdateCell(o.X, o.Y)
+	w.replan = true
+}
+
+// place a permanent source in the named scent layer
+func (w *World) AddScentSource(layer string, pos Position) {
+	w.dm.AddSource(layer, pos, 1.0, 1.0)
+}
+
+// add an obstacle at o (if it's on the grid and not already one)
 func (w *World) AddObstacle(o Position) {
-	w.obstacles = append(w.obstacles, o)
-	w.dm.AddObstacle(o)
+	if !w.dm.InGrid(o.X, o.Y) {
+		return
+	}
+	if w.obstacles.Add(o) {
+		w.dm.AddObstacle(o)
+		w.obstacleChanged(o)
+	}
+}
+
+func (w *World) RemoveObstacle(o Position) {
+	if w.obstacles.Remove(o) {
+		w.dm.RemoveObstacle(o)
+		w.obstacleChanged(o)
+	}
 }
 
 func (w *World) ClearObstacles() {
-	w.obstacles = w.obstacles[:0]
+	cleared := append([]Position(nil), w.obstacles.Cells()...)
+	w.obstacles.Clear()
 	w.dm.ClearObstacles()
+	for _, o := range cleared {
+		w.obstacleChanged(o)
+	}
 }
 
 func (w *World) RandomObstacles() {
 	for i := 0; i < 20; i++ {
 		o := Position{
-			rand.Intn(GRID_CELL_DIMENSION),
-			rand.Intn(GRID_CELL_DIMENSION),
+			X: w.rng.Intn(w.dm.Width()),
+			Y: w.rng.Intn(w.dm.Height()),
 		}
-		w.obstacles = append(w.obstacles, o)
-		w.dm.AddObstacle(o)
+		w.AddObstacle(o)
+	}
+}
+
+// a snapshot of the world as a Level. The entity's move target, if it has
+// one, is its only waypoint
+func (w *World) Level() *Level {
+	l := &Level{W: w.dm.Width(), H: w.dm.Height()}
+	l.Obstacles = append(l.Obstacles, w.obstacles.Cells()...)
+	if w.e != nil {
+		p := w.dm.ToGridSpace(w.e.pos)
+		l.Entity = &p
+		if w.e.moveTarget != nil {
+			l.Waypoints = append(l.Waypoints,
+				w.dm.ToGridSpace(*w.e.moveTarget))
+		}
+	}
+	for _, c := range w.chasers {
+		l.Chasers = append(l.Chasers, w.dm.ToGridSpace(c.pos))
+	}
+	return l
+}
+
+// replace the world's contents with the level's, starting the entity
+// toward the level's first waypoint (searching and smoothing as given). The
+// diffusion map (and with it any scent sources) is rebuilt, scaled so the
+// grid fills the world. The level is loaded even if there's no path to the
+// first waypoint, which is the error returned
+func (w *World) LoadLevel(
+	l *Level, search SearchMode, smooth SmoothMode) error {
+	dim := l.W
+	if l.H > dim {
+		dim = l.H
+	}
+	w.obstacles.Clear()
+	w.dm = NewDiffusionMap(w.dm.r, l.W, l.H,
+		float64(GRID_WORLD_DIMENSION)/float64(dim), nil)
+	w.pc = gridpath.NewPathComputer(w.dm, w.dm.Width(), w.dm.Height())
+	w.planner = gridpath.NewDStarLite(w.dm, w.dm.Width(), w.dm.Height())
+	w.replan = false
+	w.flow = NewFlowField(w.dm)
+	w.flowAgents = w.flowAgents[:0]
+	w.playerSource = nil
+	w.e = nil
+	w.chasers = w.chasers[:0]
+	var err error
+	for _, o := range l.Obstacles {
+		w.AddObstacle(o)
+	}
+	if l.Entity != nil {
+		w.e = NewEntity(w.dm.ToWorldSpace(*l.Entity), w)
+		if len(l.Waypoints) > 0 {
+			_, err = w.MoveEntityTo(
+				w.dm.ToWorldSpace(l.Waypoints[0]), search, smooth)
+		}
+	}
+	for _, c := range l.Chasers {
+		w.chasers = append(w.chasers, NewChaser(w.dm.ToWorldSpace(c), w))
 	}
+	return err
 }
@@ -1,14 +1,20 @@
-package main
+package gridpath
 
 import (
-// "math"
+	"fmt"
 )
 
-// G:			reference to the grid of cells we're pathing over
+// Grid:		reference to the grid of cells we're pathing over
+// Width:		width of the grid (the per-node arrays are Width x Height)
+// Height:		height of the grid
+// Heuristic:	estimate of the remaining cost to the end
 // OH			NodeHeap ("Open Heap") used to pop off the nodes with the lowest
 //					F during search (open nodes)
 // N: 			incremented each time we calculate (used to avoid having to
 //					clear values in various arrays)
+// buf:			scratch slice Grid.Neighbors appends into
+// Expanded:	nodes closed by the most recent search
+// Trace:		if non-nil, records every operation on OH (see Trace)
 //
 // WhichList:	2d array shadowing used to keep track of which list, open or
 //					closed, the node is on
@@ -18,9 +24,15 @@ import (
 // F:			G + H
 // HeapIX:		keeps track of the heap index of the element at this position
 type PathComputer struct {
-	WM *WorldMap
-	OH *NodeHeap
-	N  int
+	Grid      Grid
+	Width     int
+	Height    int
+	Heuristic Heuristic
+	OH        *NodeHeap
+	N         int
+	Expanded  int
+	Trace     *Trace
+	buf       []Position
 	// these 2D arrays store info about each node
 	WhichList [][]int
 	From      [][]Position
@@ -30,38 +42,44 @@ type PathComputer struct {
 	HeapIX    [][]int
 }
 
-// special value used for the "From" of the start node
-var NOWHERE = Position{-1, -1}
+func (pc *PathComputer) NodeString(x int, y int) string {
+	return fmt.Sprintf("Node{[%d, %d], G: %d, H: %d, F: %d, HeapIX: %d}",
+		x, y, pc.G[x][y], pc.H[x][y], pc.F[x][y], pc.HeapIX[x][y])
+}
 
-func NewPathComputer(wm *WorldMap) *PathComputer {
+func NewPathComputer(g Grid, w int, h int) *PathComputer {
 
 	// make 2D array rows
 	// NOTE: in array-speak, the "rows" are columns. It's just nicer to put
 	// X as the first coordinate instead of Y
-	whichList := make([][]int, WORLD_CELLWIDTH)
-	from := make([][]Position, WORLD_CELLWIDTH)
-	g := make([][]int, WORLD_CELLWIDTH)
-	h := make([][]int, WORLD_CELLWIDTH)
-	f := make([][]int, WORLD_CELLWIDTH)
-	heapIX := make([][]int, WORLD_CELLWIDTH)
+	whichList := make([][]int, w)
+	from := make([][]Position, w)
+	gs := make([][]int, w)
+	hs := make([][]int, w)
+	fs := make([][]int, w)
+	heapIX := make([][]int, w)
 	// make 2D array columns
-	for x := 0; x < WORLD_CELLWIDTH; x++ {
-		whichList[x] = make([]int, WORLD_CELLHEIGHT)
-		from[x] = make([]Position, WORLD_CELLHEIGHT)
-		g[x] = make([]int, WORLD_CELLHEIGHT)
-		h[x] = make([]int, WORLD_CELLHEIGHT)
-		f[x] = make([]int, WORLD_CELLHEIGHT)
-		heapIX[x] = make([]int, WORLD_CELLHEIGHT)
+	for x := 0; x < w; x++ {
+		whichList[x] = make([]int, h)
+		from[x] = make([]Position, h)
+		gs[x] = make([]int, h)
+		hs[x] = make([]int, h)
+		fs[x] = make([]int, h)
+		heapIX[x] = make([]int, h)
 	}
 	// make node heap
 	pc := &PathComputer{
-		WM:        wm,
+		Grid:      g,
+		Width:     w,
+		Height:    h,
+		Heuristic: EuclideanHeuristic,
 		N:         0,
+		buf:       make([]Position, 0, 8),
 		WhichList: whichList,
 		From:      from,
-		G:         g,
-		H:         h,
-		F:         f,
+		G:         gs,
+		H:         hs,
+		F:         fs,
 		HeapIX:    heapIX,
 	}
 	oh := NewNodeHeap(pc)
@@ -69,42 +87,8 @@ func NewPathComputer(wm *WorldMap) *PathComputer {
 	return pc
 }
 
-// neighbor x, y offsets
-//
-//                                       X
-//      --------------------------------->
-//     |
-//     |    -1,  1     0,  1     1,  1
-//     |
-//     |    -1,  0               1,  0
-//     |
-//     |    -1, -1     0, -1     1, -1
-//     |
-//  Y  v
-//
-//
-var neighborIXs = [][2]int{
-	[2]int{-1, 1},
-	[2]int{1, 1},
-	[2]int{-1, -1},
-	[2]int{1, -1},
-	[2]int{0, 1},
-	[2]int{-1, 0},
-	[2]int{1, 0},
-	[2]int{0, -1},
-}
-
-func (pc *PathComputer) Heuristic(p1 Position, p2 Position) int {
-	dx := p1.X - p2.X
-	dy := p1.Y - p2.Y
-	result := 10 * (dx + dy)
-	if result < 0 {
-		result *= -1
-	}
-	return result
-	// return int(10 * math.Sqrt(float64(dx*dx+dy*dy)))
-}
-
+// Path returns the cells from end back to start (inclusive), or an empty
+// slice if there is no path
 func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 	// clear the heap which contains leftover nodes from the last calculation
 	pc.OH.Clear()
@@ -112,6 +96,7 @@ func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 	// we increment by 2 since we use WhichList == pc.N for OPEN and
 	// WhichList == pc.N + 1 for CLOSED
 	pc.N += 2
+	pc.Expanded = 0
 
 	// add first node to open heap (whichlist == pc.N)
 	pc.WhichList[start.X][start.Y] = pc.N
@@ -119,7 +104,6 @@ func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 	pc.From[start.X][start.Y] = NOWHERE
 	pc.G[start.X][start.Y] = 0
 	pc.H[start.X][start.Y] = pc.Heuristic(start, end)
-	pc.WhichList[start.X][start.Y] = pc.N
 	pc.OH.Add(start)
 	// while open heap has elements...
 	for pc.OH.Len() > 0 {
@@ -132,6 +116,7 @@ func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 		}
 		// set as CLOSED (pc.N + 1)
 		pc.WhichList[cur.X][cur.Y] = pc.N + 1
+		pc.Expanded++
 		// if the current cell is the end, we're here. build the return list
 		if cur.X == end.X && cur.Y == end.Y {
 			path = make([]Position, 0)
@@ -143,35 +128,16 @@ func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 			return path
 		}
 		// else, we have yet to complete the path. So:
-		// for each neighbor
-		for _, neighborIX := range neighborIXs {
-			// get the coordinates of the cell we will check the cost to
-			// by applying an offset to cur's coordinates
-			x := cur.X + neighborIX[0]
-			y := cur.Y + neighborIX[1]
-			// continue loop to next neighbor early if not in grid
-			if !pc.WM.InGrid(x, y) {
-				continue
-			}
-			// dist is an integer expression of the distance from
-			// cur to the neighbor cell we're looking at here.
-			// if either x or y offset is 0, we're moving straight,
-			// so put 10. Otherwise we're moving diagonal, so put 14
-			// (these are 1 and sqrt(2), but made into integers for speed)
-			var dist int
-			if neighborIX[0]*neighborIX[1] == 0 {
-				dist = 10
-			} else {
-				dist = 14
-			}
-			// multiply distance by terrain cost
-			terrainCost := TERRAIN_COSTS[pc.WM.cells[x][y].kind]
-			dist = dist * terrainCost
-
-			// compute g, h, f for the current cell
+		// for each neighbor the grid says we can step to
+		pc.buf = pc.Grid.Neighbors(cur, pc.buf[:0])
+		for _, n := range pc.buf {
+			x, y := n.X, n.Y
+			// distance to the neighbor, multiplied by the terrain cost of
+			// entering it
+			dist := StepDistance(cur, n) * pc.Grid.Cost(x, y)
+			// compute g, h for the considered neighbor
 			g := pc.G[cur.X][cur.Y] + dist
-			h := pc.Heuristic(Position{x, y}, end)
-
+			h := pc.Heuristic(n, end)
 			// don't consider this neighbor if the neighbor is in the closed
 			// list *and* our g is greater or equal to its g score (we already
 			// have a better way to get to it)
@@ -190,7 +156,7 @@ func (pc *PathComputer) Path(start Position, end Position) (path []Position) {
 				// set whichlist == OPEN
 				pc.WhichList[x][y] = pc.N
 				// push to open heap
-				pc.OH.Add(Position{x, y})
+				pc.OH.Add(n)
 			} else {
 				// if it *is* on the open heap already, check to see if
 				// this is a better path to that square
@@ -0,0 +1,137 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPathStraight(t *testing.T) {
+	g := newTestGrid(
+		".....",
+		".....",
+		".....")
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 1}, Position{4, 1})
+	if len(path) != 5 {
+		t.Fatalf("expected 5 cells, got %d:\n%s", len(path), g.String(path))
+	}
+	// path runs from end back to start
+	if path[0] != (Position{4, 1}) || path[4] != (Position{0, 1}) {
+		t.Fatalf("path endpoints wrong: %v", path)
+	}
+}
+
+func TestPathAroundWall(t *testing.T) {
+	g := newTestGrid(
+		"..#..",
+		"..#..",
+		"..#..",
+		".....")
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 0}, Position{4, 0})
+	if len(path) == 0 {
+		t.Fatal("expected a path")
+	}
+	for _, p := range path {
+		if !g.Passable(p.X, p.Y) {
+			t.Fatalf("path crosses obstacle at %v:\n%s", p, g.String(path))
+		}
+	}
+	if !validPath(g, path) {
+		t.Fatalf("path has invalid steps:\n%s", g.String(path))
+	}
+}
+
+func TestPathNoCornerCutting(t *testing.T) {
+	g := newTestGrid(
+		"..",
+		"#.")
+	pc := NewPathComputer(g, g.w, g.h)
+	// the diagonal [1, 0] -> [0, 1] is flanked by the obstacle at [0, 1]'s
+	// neighbor, so it must not be taken directly
+	path := pc.Path(Position{0, 0}, Position{1, 1})
+	if len(path) != 3 {
+		t.Fatalf("expected the path to go around the corner:\n%s",
+			g.String(path))
+	}
+	g.cutCorners = true
+	path = pc.Path(Position{0, 0}, Position{1, 1})
+	if len(path) != 2 {
+		t.Fatalf("expected a diagonal step when cutting corners:\n%s",
+			g.String(path))
+	}
+}
+
+func TestPathUnreachable(t *testing.T) {
+	g := newTestGrid(
+		"..#..",
+		"..#..",
+		"..#..")
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 0}, Position{4, 2})
+	if len(path) != 0 {
+		t.Fatalf("expected no path, got:\n%s", g.String(path))
+	}
+}
+
+func TestPathPrefersCheapTerrain(t *testing.T) {
+	g := newTestGrid(
+		".......",
+		".99999.",
+		".99999.",
+		".......")
+	g.cutCorners = true
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 1}, Position{6, 1})
+	for _, p := range path {
+		if g.Cost(p.X, p.Y) > 1 {
+			t.Fatalf("path crosses expensive terrain at %v:\n%s",
+				p, g.String(path))
+		}
+	}
+}
+
+// with an admissible heuristic, A* paths cost what Dijkstra says is
+// cheapest
+func TestPathOctileIsOptimal(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		g := randomWeightedGrid(30, 30, 0.1, seed)
+		g.cutCorners = true
+		pc := NewPathComputer(g, g.w, g.h)
+		pc.Heuristic = OctileHeuristic
+		r := rand.New(rand.NewSource(seed))
+		for i := 0; i < 20; i++ {
+			start := randomOpenCell(g, r)
+			end := randomOpenCell(g, r)
+			cost := weightedPathCost(g, reversed(pc.Path(start, end)))
+			pc.Dijkstra(start)
+			if pc.Reached(end.X, end.Y) && cost != pc.G[end.X][end.Y] {
+				t.Fatalf("%v->%v: A* cost %d, cheapest %d",
+					start, end, cost, pc.G[end.X][end.Y])
+			}
+		}
+	}
+}
+
+func TestPathRepeatedSearches(t *testing.T) {
+	// the generation counter N means nothing is cleared between searches;
+	// make sure leftover state doesn't leak into the next result
+	g := newTestGrid(
+		"......",
+		".####.",
+		"......")
+	pc := NewPathComputer(g, g.w, g.h)
+	first := pc.Path(Position{0, 0}, Position{5, 2})
+	pc.Path(Position{5, 0}, Position{0, 0})
+	pc.Path(Position{0, 2}, Position{5, 2})
+	again := pc.Path(Position{0, 0}, Position{5, 2})
+	if len(first) != len(again) {
+		t.Fatalf("repeated search differs:\n%s\nvs\n%s",
+			g.String(first), g.String(again))
+	}
+	for i := range first {
+		if first[i] != again[i] {
+			t.Fatalf("repeated search differs at %d", i)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package gridpath
 
 import (
 	"bytes"
@@ -45,6 +45,9 @@ func (h *NodeHeap) Add(p Position) (ix int) {
 	ix = len(h.Arr) - 1
 	h.PC.HeapIX[p.X][p.Y] = ix
 	ix = h.bubbleUp(ix)
+	if h.PC.Trace != nil {
+		h.PC.Trace.record(TRACE_PUSH, h.PC, p)
+	}
 	// return ix to user
 	return ix
 }
@@ -104,6 +107,9 @@ func (h *NodeHeap) Pop() (Position, error) {
 	h.Arr = h.Arr[:last_ix]
 	// bubble element down to its place
 	h.bubbleDown(1)
+	if h.PC.Trace != nil {
+		h.PC.Trace.record(TRACE_POP, h.PC, p)
+	}
 	return p, nil
 }
 
@@ -116,6 +122,9 @@ func (h *NodeHeap) Modify(ix int, G int) {
 	F := G + h.PC.H[h.Arr[ix].X][h.Arr[ix].Y]
 	// assign the new F value
 	h.PC.F[h.Arr[ix].X][h.Arr[ix].Y] = F
+	if h.PC.Trace != nil {
+		h.PC.Trace.record(TRACE_MODIFY, h.PC, h.Arr[ix])
+	}
 	// bubble up if needed (setting HeapIX)
 	if F < oldVal {
 		h.bubbleUp(ix)
@@ -0,0 +1,51 @@
+package gridpath
+
+// Dijkstra floods outward from start over the whole grid (a uniform-cost
+// search, no heuristic and no end), leaving G and From filled in for
+// every cell reachable from start. Since nothing is cleared between
+// searches, G holds stale values for the other cells; use Reached to
+// tell them apart
+func (pc *PathComputer) Dijkstra(start Position) {
+	pc.OH.Clear()
+	pc.N += 2
+	pc.Expanded = 0
+
+	pc.WhichList[start.X][start.Y] = pc.N
+	pc.From[start.X][start.Y] = NOWHERE
+	pc.G[start.X][start.Y] = 0
+	pc.H[start.X][start.Y] = 0
+	pc.OH.Add(start)
+	for pc.OH.Len() > 0 {
+		cur, err := pc.OH.Pop()
+		if err != nil {
+			return
+		}
+		pc.WhichList[cur.X][cur.Y] = pc.N + 1
+		pc.Expanded++
+		pc.buf = pc.Grid.Neighbors(cur, pc.buf[:0])
+		for _, n := range pc.buf {
+			x, y := n.X, n.Y
+			// with no heuristic, a closed node already has its final G
+			if pc.WhichList[x][y] == pc.N+1 {
+				continue
+			}
+			g := pc.G[cur.X][cur.Y] + StepDistance(cur, n)*pc.Grid.Cost(x, y)
+			if pc.WhichList[x][y] != pc.N {
+				pc.From[x][y] = cur
+				pc.G[x][y] = g
+				pc.H[x][y] = 0
+				pc.WhichList[x][y] = pc.N
+				pc.OH.Add(n)
+			} else if g < pc.G[x][y] {
+				pc.From[x][y] = cur
+				pc.OH.Modify(pc.HeapIX[x][y], g)
+			}
+		}
+	}
+}
+
+// Reached reports whether x, y was closed by the most recent search (for
+// Dijkstra, whether G[x][y] is the cost from its start)
+func (pc *PathComputer) Reached(x int, y int) bool {
+	return pc.WhichList[x][y] == pc.N+1
+}
@@ -0,0 +1,321 @@
+package gridpath
+
+// D* Lite (Koenig & Likhachev): an incremental planner which keeps its
+// search between calls, searching backward from the goal so that when
+// cells change under an agent that has moved on from where it started,
+// only the part of the search the change affects is repaired.
+//
+// g and rhs use the same generation trick as PathComputer.N: a value only
+// counts if its cell's gen matches N, otherwise it's DSTAR_INF, so Reset
+// doesn't have to clear the arrays.
+
+// the cost of a cell that can't reach the goal (or isn't known to yet)
+const DSTAR_INF = 1 << 30
+
+// Grid:		the grid being planned over
+// Width:		width of the grid
+// Height:		height of the grid
+// N:			incremented on each Reset (see gen)
+// Expanded:	nodes popped from the open queue by the last Compute
+//
+// start:		where the agent is (paths are planned from here)
+// last:		start as of the last change to km
+// goal:		where the agent is going (the search's root)
+// km:			heuristic offset accumulated as the agent moves (keeps old keys valid)
+// active:		whether Reset has been called
+// gen:			the N with which g and rhs were last set for a cell
+// g:			cost to the goal as of the last time the cell was expanded
+// rhs:			one-step lookahead of g (best successor's g plus the step)
+// k1, k2:		the cell's key while it's in the queue
+// heapIX:		the cell's index in queue, or 0 if it isn't in it
+// queue:		binary heap (index 0 unused) of inconsistent cells
+type DStarLite struct {
+	Grid     Grid
+	Width    int
+	Height   int
+	N        int
+	Expanded int
+
+	start  Position
+	last   Position
+	goal   Position
+	km     int
+	active bool
+
+	gen    [][]int
+	g      [][]int
+	rhs    [][]int
+	k1     [][]int
+	k2     [][]int
+	heapIX [][]int
+	queue  []Position
+	buf    []Position
+	preds  []Position
+}
+
+func NewDStarLite(g Grid, w int, h int) *DStarLite {
+	ds := &DStarLite{
+		Grid:   g,
+		Width:  w,
+		Height: h,
+		queue:  []Position{NOWHERE},
+		buf:    make([]Position, 0, 8),
+		preds:  make([]Position, 0, 8),
+	}
+	for _, arr := range []*[][]int{
+		&ds.gen, &ds.g, &ds.rhs, &ds.k1, &ds.k2, &ds.heapIX} {
+		*arr = make([][]int, w)
+		for x := 0; x < w; x++ {
+			(*arr)[x] = make([]int, h)
+		}
+	}
+	return ds
+}
+
+// Reset starts planning afresh from start to goal (both on the grid; see
+// CheckEndpoints). Call Compute to search
+func (ds *DStarLite) Reset(start Position, goal Position) {
+	for _, p := range ds.queue[1:] {
+		ds.heapIX[p.X][p.Y] = 0
+	}
+	ds.queue = ds.queue[:1]
+	ds.N++
+	ds.start, ds.last, ds.goal = start, start, goal
+	ds.km = 0
+	ds.active = true
+	ds.touch(goal)
+	ds.rhs[goal.X][goal.Y] = 0
+	ds.push(goal)
+}
+
+// whether Reset has been called
+func (ds *DStarLite) Active() bool {
+	return ds.active
+}
+
+func (ds *DStarLite) Goal() Position {
+	return ds.goal
+}
+
+// MoveStart tells the planner the agent is now at start, to be called
+// before Compute once it has moved
+func (ds *DStarLite) MoveStart(start Position) {
+	if !ds.active || start == ds.start {
+		return
+	}
+	ds.km += OctileDistance(ds.last, start)
+	ds.last = start
+	ds.start = start
+}
+
+// UpdateCell must be called after the passability or cost of x, y
+// changes. The cell and its neighbours (whose steps into it, or past its
+// corners, changed) are queued to be repaired by the next Compute
+func (ds *DStarLite) UpdateCell(x int, y int) {
+	if !ds.active {
+		return
+	}
+	ds.updateVertex(Position{x, y})
+	for _, ix := range NeighborIXs {
+		nx, ny := x+ix[0], y+ix[1]
+		if ds.Grid.InGrid(nx, ny) {
+			ds.updateVertex(Position{nx, ny})
+		}
+	}
+}
+
+// Compute searches (or repairs the search) until start's cost to the goal
+// is known
+func (ds *DStarLite) Compute() {
+	ds.Expanded = 0
+	if !ds.active {
+		return
+	}
+	s := ds.start
+	for len(ds.queue) > 1 {
+		u := ds.queue[1]
+		sk1, sk2 := ds.key(s)
+		if !keyLess(ds.k1[u.X][u.Y], ds.k2[u.X][u.Y], sk1, sk2) &&
+			ds.rhsOf(s) == ds.gOf(s) {
+			break
+		}
+		ds.Expanded++
+		old1, old2 := ds.k1[u.X][u.Y], ds.k2[u.X][u.Y]
+		new1, new2 := ds.key(u)
+		if keyLess(old1, old2, new1, new2) {
+			ds.k1[u.X][u.Y], ds.k2[u.X][u.Y] = new1, new2
+			ds.down(1)
+			continue
+		}
+		ds.remove(u)
+		// u's predecessors are its neighbours (steps on the grid go both
+		// ways), whose rhs may change with u's g
+		overconsistent := ds.gOf(u) > ds.rhsOf(u)
+		if overconsistent {
+			ds.g[u.X][u.Y] = ds.rhsOf(u)
+		} else {
+			ds.g[u.X][u.Y] = DSTAR_INF
+		}
+		ds.preds = ds.Grid.Neighbors(u, ds.preds[:0])
+		for _, p := range ds.preds {
+			ds.updateVertex(p)
+		}
+		if !overconsistent {
+			ds.updateVertex(u)
+		}
+	}
+}
+
+// Path returns the planned cells from the goal back to start (inclusive),
+// in the same order as PathComputer.Path, or an empty slice if the goal
+// can't be reached. Call Compute first
+func (ds *DStarLite) Path() []Position {
+	if !ds.active || ds.gOf(ds.start) >= DSTAR_INF {
+		return []Position{}
+	}
+	path := []Position{ds.start}
+	cur := ds.start
+	for cur != ds.goal && len(path) <= ds.Width*ds.Height {
+		next, best := NOWHERE, DSTAR_INF
+		ds.buf = ds.Grid.Neighbors(cur, ds.buf[:0])
+		for _, n := range ds.buf {
+			if c := ds.stepCost(cur, n) + ds.gOf(n); c < best {
+				next, best = n, c
+			}
+		}
+		if next == NOWHERE {
+			return []Position{}
+		}
+		path = append(path, next)
+		cur = next
+	}
+	// ran out of cells without reaching the goal: g is inconsistent (e.g.
+	// Compute wasn't called after a change)
+	if cur != ds.goal {
+		return []Position{}
+	}
+	return reversed(path)
+}
+
+// the cost of stepping from a into its neighbour b
+func (ds *DStarLite) stepCost(a Position, b Position) int {
+	return StepDistance(a, b) * ds.Grid.Cost(b.X, b.Y)
+}
+
+// make sure g and rhs at p belong to this generation
+func (ds *DStarLite) touch(p Position) {
+	if ds.gen[p.X][p.Y] != ds.N {
+		ds.gen[p.X][p.Y] = ds.N
+		ds.g[p.X][p.Y] = DSTAR_INF
+		ds.rhs[p.X][p.Y] = DSTAR_INF
+	}
+}
+
+func (ds *DStarLite) gOf(p Position) int {
+	if ds.gen[p.X][p.Y] != ds.N {
+		return DSTAR_INF
+	}
+	return ds.g[p.X][p.Y]
+}
+
+func (ds *DStarLite) rhsOf(p Position) int {
+	if ds.gen[p.X][p.Y] != ds.N {
+		return DSTAR_INF
+	}
+	return ds.rhs[p.X][p.Y]
+}
+
+func (ds *DStarLite) key(p Position) (int, int) {
+	m := ds.gOf(p)
+	if r := ds.rhsOf(p); r < m {
+		m = r
+	}
+	if m >= DSTAR_INF {
+		return DSTAR_INF, DSTAR_INF
+	}
+	return m + OctileDistance(ds.start, p) + ds.km, m
+}
+
+func keyLess(a1 int, a2 int, b1 int, b2 int) bool {
+	return a1 < b1 || (a1 == b1 && a2 < b2)
+}
+
+// recompute p's rhs from its successors and queue it if it's inconsistent
+func (ds *DStarLite) updateVertex(p Position) {
+	ds.touch(p)
+	if p != ds.goal {
+		best := DSTAR_INF
+		if ds.Grid.Passable(p.X, p.Y) {
+			ds.buf = ds.Grid.Neighbors(p, ds.buf[:0])
+			for _, n := range ds.buf {
+				if g := ds.gOf(n); g < DSTAR_INF {
+					if c := ds.stepCost(p, n) + g; c < best {
+						best = c
+					}
+				}
+			}
+		}
+		ds.rhs[p.X][p.Y] = best
+	}
+	if ds.heapIX[p.X][p.Y] != 0 {
+		ds.remove(p)
+	}
+	if ds.g[p.X][p.Y] != ds.rhs[p.X][p.Y] {
+		ds.push(p)
+	}
+}
+
+func (ds *DStarLite) less(i int, j int) bool {
+	a, b := ds.queue[i], ds.queue[j]
+	return keyLess(ds.k1[a.X][a.Y], ds.k2[a.X][a.Y],
+		ds.k1[b.X][b.Y], ds.k2[b.X][b.Y])
+}
+
+func (ds *DStarLite) swap(i int, j int) {
+	ds.queue[i], ds.queue[j] = ds.queue[j], ds.queue[i]
+	ds.heapIX[ds.queue[i].X][ds.queue[i].Y] = i
+	ds.heapIX[ds.queue[j].X][ds.queue[j].Y] = j
+}
+
+func (ds *DStarLite) up(i int) {
+	for i > 1 && ds.less(i, i>>1) {
+		ds.swap(i, i>>1)
+		i >>= 1
+	}
+}
+
+func (ds *DStarLite) down(i int) {
+	for {
+		least := i
+		if l := i << 1; l < len(ds.queue) && ds.less(l, least) {
+			least = l
+		}
+		if r := i<<1 + 1; r < len(ds.queue) && ds.less(r, least) {
+			least = r
+		}
+		if least == i {
+			return
+		}
+		ds.swap(i, least)
+		i = least
+	}
+}
+
+func (ds *DStarLite) push(p Position) {
+	ds.k1[p.X][p.Y], ds.k2[p.X][p.Y] = ds.key(p)
+	ds.queue = append(ds.queue, p)
+	ds.heapIX[p.X][p.Y] = len(ds.queue) - 1
+	ds.up(len(ds.queue) - 1)
+}
+
+func (ds *DStarLite) remove(p Position) {
+	i := ds.heapIX[p.X][p.Y]
+	last := len(ds.queue) - 1
+	ds.swap(i, last)
+	ds.queue = ds.queue[:last]
+	ds.heapIX[p.X][p.Y] = 0
+	if i < last {
+		ds.up(i)
+		ds.down(i)
+	}
+}
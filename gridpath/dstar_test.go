@@ -0,0 +1,163 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// D* Lite's path from start should cost the same as A*'s
+func checkDStar(t *testing.T, g *testGrid, ds *DStarLite,
+	pc *PathComputer, start Position, goal Position) {
+	t.Helper()
+	ds.Compute()
+	path := ds.Path()
+	want := pc.Path(start, goal)
+	if (len(path) == 0) != (len(want) == 0) {
+		t.Fatalf("%v -> %v: D* Lite found %d cells, A* %d",
+			start, goal, len(path), len(want))
+	}
+	if len(path) == 0 {
+		return
+	}
+	if path[0] != goal || path[len(path)-1] != start {
+		t.Fatalf("path runs %v -> %v, want %v -> %v",
+			path[0], path[len(path)-1], goal, start)
+	}
+	if !validPath(g, path) {
+		t.Fatalf("invalid path:\n%s", g.String(path))
+	}
+	// both run goal first; weightedPathCost wants them start first
+	a := weightedPathCost(g, reversed(path))
+	if b := weightedPathCost(g, reversed(want)); a != b {
+		t.Fatalf("%v -> %v: D* Lite path costs %d, A* %d",
+			start, goal, a, b)
+	}
+}
+
+func TestDStarLiteMatchesAStar(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for seed := int64(0); seed < 10; seed++ {
+		g := randomWeightedGrid(32, 32, 0.2, seed)
+		ds := NewDStarLite(g, g.w, g.h)
+		pc := NewPathComputer(g, g.w, g.h)
+		for i := 0; i < 10; i++ {
+			start, goal := randomOpenCell(g, r), randomOpenCell(g, r)
+			ds.Reset(start, goal)
+			checkDStar(t, g, ds, pc, start, goal)
+		}
+	}
+}
+
+// stale g values that walk Path in circles mean no path, not a partial one
+func TestDStarLitePathInconsistent(t *testing.T) {
+	g := newTestGrid("......")
+	ds := NewDStarLite(g, g.w, g.h)
+	ds.Reset(Position{0, 0}, Position{5, 0})
+	ds.Compute()
+	for x := 0; x < g.w; x++ {
+		ds.touch(Position{x, 0})
+		ds.g[x][0] = 0
+	}
+	if path := ds.Path(); len(path) != 0 {
+		t.Fatalf("Path returned %d cells without reaching the goal:\n%s",
+			len(path), g.String(path))
+	}
+}
+
+// walk along the path, toggling random cells as we go, and check the
+// repaired path against a fresh A* search each time
+func TestDStarLiteRepairs(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for seed := int64(0); seed < 10; seed++ {
+		g := randomWeightedGrid(32, 32, 0.2, seed)
+		ds := NewDStarLite(g, g.w, g.h)
+		pc := NewPathComputer(g, g.w, g.h)
+		start, goal := randomOpenCell(g, r), randomOpenCell(g, r)
+		ds.Reset(start, goal)
+		checkDStar(t, g, ds, pc, start, goal)
+		for step := 0; step < 20; step++ {
+			if path := ds.Path(); len(path) > 2 {
+				start = path[len(path)-2]
+				ds.MoveStart(start)
+			}
+			for i := 0; i < 5; i++ {
+				p := Position{r.Intn(g.w), r.Intn(g.h)}
+				if p == start || p == goal {
+					continue
+				}
+				if g.cells[p.X][p.Y] == '#' {
+					g.cells[p.X][p.Y] = '.'
+				} else {
+					g.cells[p.X][p.Y] = '#'
+				}
+				ds.UpdateCell(p.X, p.Y)
+			}
+			checkDStar(t, g, ds, pc, start, goal)
+		}
+	}
+}
+
+func TestDStarLiteRepairIsLocal(t *testing.T) {
+	g := randomGrid(64, 64, 0.2, 3)
+	ds := NewDStarLite(g, g.w, g.h)
+	start, goal := Position{2, 32}, Position{61, 32}
+	g.cells[start.X][start.Y] = '.'
+	g.cells[goal.X][goal.Y] = '.'
+	ds.Reset(start, goal)
+	ds.Compute()
+	path := ds.Path()
+	if len(path) < 10 {
+		t.Fatalf("want a longer path to block: %v", path)
+	}
+	// block the path halfway along, and compare the repair with searching
+	// from scratch
+	mid := path[len(path)/2]
+	g.cells[mid.X][mid.Y] = '#'
+	ds.UpdateCell(mid.X, mid.Y)
+	ds.Compute()
+	fresh := NewDStarLite(g, g.w, g.h)
+	fresh.Reset(start, goal)
+	fresh.Compute()
+	if ds.Expanded >= fresh.Expanded {
+		t.Fatalf("repair expanded %d nodes, searching afresh %d",
+			ds.Expanded, fresh.Expanded)
+	}
+	for _, p := range ds.Path() {
+		if p == mid {
+			t.Fatalf("repaired path goes through the new obstacle at %v", p)
+		}
+	}
+	// a change nowhere near the search costs nothing
+	far := Position{0, 0}
+	for _, p := range []Position{{0, 0}, {63, 0}, {0, 63}, {63, 63}} {
+		if OctileDistance(p, start)+OctileDistance(p, goal) >
+			OctileDistance(far, start)+OctileDistance(far, goal) {
+			far = p
+		}
+	}
+	g.cells[far.X][far.Y] = '#'
+	ds.UpdateCell(far.X, far.Y)
+	ds.Compute()
+	if ds.Expanded > 10 {
+		t.Fatalf("far-away change at %v expanded %d nodes", far, ds.Expanded)
+	}
+}
+
+func BenchmarkDStarLiteRepair256(b *testing.B) {
+	g := randomWeightedGrid(256, 256, 0.1, 1)
+	ds := NewDStarLite(g, g.w, g.h)
+	r := rand.New(rand.NewSource(1))
+	ds.Reset(randomOpenCell(g, r), randomOpenCell(g, r))
+	ds.Compute()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := Position{r.Intn(g.w), r.Intn(g.h)}
+		if g.cells[p.X][p.Y] == '#' {
+			g.cells[p.X][p.Y] = '.'
+		} else {
+			g.cells[p.X][p.Y] = '#'
+		}
+		ds.UpdateCell(p.X, p.Y)
+		ds.Compute()
+	}
+}
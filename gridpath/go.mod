@@ -0,0 +1,5 @@
+module github.com/dt-rush/gamedev-sketchbook/gridpath
+
+go 1.19
+
+require github.com/disiqueira/gotree v1.0.0
@@ -0,0 +1,77 @@
+package gridpath
+
+// Grid is anything that can be searched cell by cell. Cells are addressed
+// by x, y with x as the first coordinate.
+type Grid interface {
+	// whether x, y is inside the bounds of the grid
+	InGrid(x int, y int) bool
+	// whether x, y can be entered at all
+	Passable(x int, y int) bool
+	// multiplier applied to the distance of a step *into* x, y (1 for a
+	// uniform-cost grid)
+	Cost(x int, y int) int
+	// appends to buf the cells reachable in a single step from p and
+	// returns the extended slice (buf is reused between calls to avoid
+	// allocating per node)
+	Neighbors(p Position, buf []Position) []Position
+}
+
+// neighbor x, y offsets
+//
+//	                                     X
+//	    --------------------------------->
+//	   |
+//	   |    -1,  1     0,  1     1,  1
+//	   |
+//	   |    -1,  0               1,  0
+//	   |
+//	   |    -1, -1     0, -1     1, -1
+//	   |
+//	Y  v
+//
+// split into two lists to allow separation of logic for diagonals
+var NeighborIXs = [][2]int{
+	[2]int{-1, 1},
+	[2]int{1, 1},
+	[2]int{-1, -1},
+	[2]int{1, -1},
+	[2]int{0, 1},
+	[2]int{-1, 0},
+	[2]int{1, 0},
+	[2]int{0, -1},
+}
+
+// AppendNeighbors is the usual 8-connected Neighbors implementation: it
+// appends every in-grid, passable cell around p to buf. If cutCorners is
+// false, a diagonal step flanked by an impassable cell on either side is
+// left out (so entities don't clip the corners of obstacles)
+func AppendNeighbors(
+	g Grid, p Position, buf []Position, cutCorners bool) []Position {
+
+	for _, neighborIX := range NeighborIXs {
+		x := p.X + neighborIX[0]
+		y := p.Y + neighborIX[1]
+		if !g.InGrid(x, y) || !g.Passable(x, y) {
+			continue
+		}
+		isDiagonal := neighborIX[0]*neighborIX[1] != 0
+		if isDiagonal && !cutCorners &&
+			(!g.Passable(p.X+neighborIX[0], p.Y) ||
+				!g.Passable(p.X, p.Y+neighborIX[1])) {
+			continue
+		}
+		buf = append(buf, Position{x, y})
+	}
+	return buf
+}
+
+// StepDistance is an integer expression of the distance between two
+// neighboring cells. If either x or y offset is 0, we're moving straight,
+// so put 10. Otherwise we're moving diagonal, so put 14 (these are 1 and
+// sqrt(2), but made into integers for speed)
+func StepDistance(from Position, to Position) int {
+	if from.X == to.X || from.Y == to.Y {
+		return 10
+	}
+	return 14
+}
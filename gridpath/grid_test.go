@@ -0,0 +1,88 @@
+package gridpath
+
+import (
+	"strings"
+)
+
+// testGrid is a Grid built from rows of text, top row first:
+//
+//	'.' open, cost 1
+//	'#' obstacle
+//	'0'-'9' open, with that cost
+//
+// x runs along a row and y runs down the rows
+type testGrid struct {
+	w, h       int
+	cells      [][]byte
+	cutCorners bool
+}
+
+func newTestGrid(rows ...string) *testGrid {
+	g := &testGrid{w: len(rows[0]), h: len(rows)}
+	g.cells = make([][]byte, g.w)
+	for x := 0; x < g.w; x++ {
+		g.cells[x] = make([]byte, g.h)
+		for y := 0; y < g.h; y++ {
+			g.cells[x][y] = rows[y][x]
+		}
+	}
+	return g
+}
+
+func (g *testGrid) InGrid(x int, y int) bool {
+	return x >= 0 && x < g.w && y >= 0 && y < g.h
+}
+
+func (g *testGrid) Passable(x int, y int) bool {
+	return g.InGrid(x, y) && g.cells[x][y] != '#'
+}
+
+func (g *testGrid) Cost(x int, y int) int {
+	c := g.cells[x][y]
+	if c >= '0' && c <= '9' {
+		return int(c - '0')
+	}
+	return 1
+}
+
+func (g *testGrid) Neighbors(p Position, buf []Position) []Position {
+	return AppendNeighbors(g, p, buf, g.cutCorners)
+}
+
+// String draws the grid with path cells marked '*'
+func (g *testGrid) String(path []Position) string {
+	onPath := make(map[Position]bool)
+	for _, p := range path {
+		onPath[p] = true
+	}
+	var b strings.Builder
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if onPath[Position{x, y}] {
+				b.WriteByte('*')
+			} else {
+				b.WriteByte(g.cells[x][y])
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// validPath checks that consecutive cells of path are neighbors according
+// to the grid
+func validPath(g Grid, path []Position) bool {
+	for i := 0; i+1 < len(path); i++ {
+		ok := false
+		for _, n := range g.Neighbors(path[i], nil) {
+			if n == path[i+1] {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package gridpath
+
+import (
+	"math"
+)
+
+// a Heuristic estimates the cost between two cells, in the same units as
+// StepDistance (10 per straight step)
+type Heuristic func(p1 Position, p2 Position) int
+
+func EuclideanHeuristic(p1 Position, p2 Position) int {
+	dx := p1.X - p2.X
+	dy := p1.Y - p2.Y
+	return int(10 * math.Sqrt(float64(dx*dx+dy*dy)))
+}
+
+func ManhattanHeuristic(p1 Position, p2 Position) int {
+	dx := p1.X - p2.X
+	if dx < 0 {
+		dx *= -1
+	}
+	dy := p1.Y - p2.Y
+	if dy < 0 {
+		dy *= -1
+	}
+	return 10 * (dx + dy)
+}
+
+// OctileHeuristic is OctileDistance: exact on an open uniform 8-connected
+// grid, so it never overestimates when every cell costs at least 1 (unlike
+// ManhattanHeuristic, which charges 20 for a diagonal step costing 14)
+func OctileHeuristic(p1 Position, p2 Position) int {
+	return OctileDistance(p1, p2)
+}
@@ -0,0 +1,577 @@
+package gridpath
+
+import (
+	"container/heap"
+)
+
+// HPA* (Botea, Müller & Schaeffer): the grid is chunked into square
+// clusters, transitions are placed where clusters border each other, and
+// the costs between the transitions of each cluster are precomputed. A
+// query then searches the (small) abstract graph of transitions and
+// refines each hop with a search confined to a single cluster, so no
+// per-cell arrays are ever allocated for the whole grid.
+
+// the longest run of open border cells that gets a single transition in
+// its middle; longer runs get one at each end
+const HPA_MAX_SINGLE_ENTRANCE = 6
+
+// Grid:			the grid being abstracted
+// Width, Height:	dimensions of Grid
+// ClusterSize:		width and height of a cluster in cells
+// CW, CH:			number of clusters across and down
+// Heuristic:		used both on the abstract graph and within clusters
+// Expanded:		nodes closed by the last Path, abstract and within clusters
+//
+// nodes:			abstract nodes by id
+// nodeAt:			id of the abstract node at a cell, if any
+// borders:			transitions crossing each border between two clusters
+// clusterNodes:	ids of the abstract nodes inside each cluster
+// window:			the Grid confined to a single cluster
+// local:			ClusterSize x ClusterSize PathComputer searching window
+type HPA struct {
+	Grid        Grid
+	Width       int
+	Height      int
+	ClusterSize int
+	CW          int
+	CH          int
+	Heuristic   Heuristic
+	Expanded    int
+
+	nodes        map[int]*hpaNode
+	nodeAt       map[Position]int
+	nextID       int
+	borders      map[hpaBorder][]hpaTransition
+	clusterNodes map[Position][]int
+	window       *windowGrid
+	local        *PathComputer
+}
+
+// refs counts the transitions (and in-flight queries) using the node; it
+// is removed from the abstract graph when that reaches 0
+type hpaNode struct {
+	id      int
+	pos     Position
+	cluster Position
+	refs    int
+	edges   []hpaEdge
+}
+
+// inter edges cross a border into the neighboring cluster; the others
+// are paths within the cluster
+type hpaEdge struct {
+	to    int
+	cost  int
+	inter bool
+}
+
+// the border between cluster a and the cluster to its right (vertical)
+// or below it (!vertical)
+type hpaBorder struct {
+	a        Position
+	vertical bool
+}
+
+type hpaTransition struct {
+	a int
+	b int
+}
+
+func NewHPA(g Grid, w int, h int, clusterSize int) *HPA {
+	window := &windowGrid{g: g, w: clusterSize, h: clusterSize}
+	hpa := &HPA{
+		Grid:        g,
+		Width:       w,
+		Height:      h,
+		ClusterSize: clusterSize,
+		CW:          (w + clusterSize - 1) / clusterSize,
+		CH:          (h + clusterSize - 1) / clusterSize,
+		Heuristic:   EuclideanHeuristic,
+		window:      window,
+		local:       NewPathComputer(window, clusterSize, clusterSize),
+	}
+	hpa.Build()
+	return hpa
+}
+
+// Build (re)computes the whole abstract graph
+func (hpa *HPA) Build() {
+	hpa.nodes = make(map[int]*hpaNode)
+	hpa.nodeAt = make(map[Position]int)
+	hpa.borders = make(map[hpaBorder][]hpaTransition)
+	hpa.clusterNodes = make(map[Position][]int)
+	for cx := 0; cx < hpa.CW; cx++ {
+		for cy := 0; cy < hpa.CH; cy++ {
+			c := Position{cx, cy}
+			if cx+1 < hpa.CW {
+				hpa.rebuildBorder(hpaBorder{c, true})
+			}
+			if cy+1 < hpa.CH {
+				hpa.rebuildBorder(hpaBorder{c, false})
+			}
+		}
+	}
+	for cx := 0; cx < hpa.CW; cx++ {
+		for cy := 0; cy < hpa.CH; cy++ {
+			hpa.rebuildCluster(Position{cx, cy})
+		}
+	}
+}
+
+// UpdateCell must be called after the passability or cost of x, y
+// changes. Only the cluster containing it is rebuilt, plus, if the cell
+// lies on the edge of its cluster, the transitions on that border (and
+// the costs within the cluster across it, whose transitions moved)
+func (hpa *HPA) UpdateCell(x int, y int) {
+	c := hpa.ClusterOf(Position{x, y})
+	x0, y0, w, h := hpa.clusterBounds(c)
+	rebuild := []Position{c}
+	if x == x0 && c.X > 0 {
+		left := Position{c.X - 1, c.Y}
+		hpa.rebuildBorder(hpaBorder{left, true})
+		rebuild = append(rebuild, left)
+	}
+	if x == x0+w-1 && c.X+1 < hpa.CW {
+		hpa.rebuildBorder(hpaBorder{c, true})
+		rebuild = append(rebuild, Position{c.X + 1, c.Y})
+	}
+	if y == y0 && c.Y > 0 {
+		up := Position{c.X, c.Y - 1}
+		hpa.rebuildBorder(hpaBorder{up, false})
+		rebuild = append(rebuild, up)
+	}
+	if y == y0+h-1 && c.Y+1 < hpa.CH {
+		hpa.rebuildBorder(hpaBorder{c, false})
+		rebuild = append(rebuild, Position{c.X, c.Y + 1})
+	}
+	for _, c := range rebuild {
+		hpa.rebuildCluster(c)
+	}
+}
+
+// Nodes is the number of nodes in the abstract graph
+func (hpa *HPA) Nodes() int {
+	return len(hpa.nodes)
+}
+
+func (hpa *HPA) ClusterOf(p Position) Position {
+	return Position{p.X / hpa.ClusterSize, p.Y / hpa.ClusterSize}
+}
+
+// top-left cell and dimensions of a cluster (clusters on the right and
+// bottom edges may be smaller than ClusterSize)
+func (hpa *HPA) clusterBounds(c Position) (x0 int, y0 int, w int, h int) {
+	x0 = c.X * hpa.ClusterSize
+	y0 = c.Y * hpa.ClusterSize
+	w = hpa.ClusterSize
+	if x0+w > hpa.Width {
+		w = hpa.Width - x0
+	}
+	h = hpa.ClusterSize
+	if y0+h > hpa.Height {
+		h = hpa.Height - y0
+	}
+	return x0, y0, w, h
+}
+
+func (hpa *HPA) walkable(p Position) bool {
+	return hpa.Grid.InGrid(p.X, p.Y) && hpa.Grid.Passable(p.X, p.Y)
+}
+
+// acquire returns the id of the node at p, creating it if needed
+func (hpa *HPA) acquire(p Position) int {
+	if id, ok := hpa.nodeAt[p]; ok {
+		hpa.nodes[id].refs++
+		return id
+	}
+	id := hpa.nextID
+	hpa.nextID++
+	c := hpa.ClusterOf(p)
+	hpa.nodes[id] = &hpaNode{id: id, pos: p, cluster: c, refs: 1}
+	hpa.nodeAt[p] = id
+	hpa.clusterNodes[c] = append(hpa.clusterNodes[c], id)
+	return id
+}
+
+// release drops a reference to a node, removing it once unused. Edges
+// pointing to it from its own cluster are left for rebuildCluster (or
+// dropTemp) to clean up
+func (hpa *HPA) release(id int) {
+	n := hpa.nodes[id]
+	n.refs--
+	if n.refs > 0 {
+		return
+	}
+	delete(hpa.nodes, id)
+	delete(hpa.nodeAt, n.pos)
+	ids := hpa.clusterNodes[n.cluster]
+	for i, other := range ids {
+		if other == id {
+			hpa.clusterNodes[n.cluster] = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// removeEdges drops the edges from a to b of the given kind
+func (hpa *HPA) removeEdges(a int, b int, inter bool) {
+	n := hpa.nodes[a]
+	kept := n.edges[:0]
+	for _, e := range n.edges {
+		if e.to != b || e.inter != inter {
+			kept = append(kept, e)
+		}
+	}
+	n.edges = kept
+}
+
+func (hpa *HPA) rebuildBorder(b hpaBorder) {
+	// take down the old transitions
+	for _, t := range hpa.borders[b] {
+		hpa.removeEdges(t.a, t.b, true)
+		hpa.removeEdges(t.b, t.a, true)
+		hpa.release(t.a)
+		hpa.release(t.b)
+	}
+	// walk along the border on a's side, with across being the step to
+	// the cell on the other side
+	x0, y0, w, h := hpa.clusterBounds(b.a)
+	var first, step, across Position
+	var n int
+	if b.vertical {
+		first = Position{x0 + w - 1, y0}
+		step = Position{0, 1}
+		across = Position{1, 0}
+		n = h
+	} else {
+		first = Position{x0, y0 + h - 1}
+		step = Position{1, 0}
+		across = Position{0, 1}
+		n = w
+	}
+	cell := func(i int) Position {
+		return Position{first.X + i*step.X, first.Y + i*step.Y}
+	}
+	open := func(i int) bool {
+		p := cell(i)
+		return hpa.walkable(p) &&
+			hpa.walkable(Position{p.X + across.X, p.Y + across.Y})
+	}
+	transitions := make([]hpaTransition, 0)
+	add := func(i int) {
+		p := cell(i)
+		q := Position{p.X + across.X, p.Y + across.Y}
+		a := hpa.acquire(p)
+		b := hpa.acquire(q)
+		hpa.nodes[a].edges = append(hpa.nodes[a].edges,
+			hpaEdge{to: b, cost: 10 * hpa.Grid.Cost(q.X, q.Y), inter: true})
+		hpa.nodes[b].edges = append(hpa.nodes[b].edges,
+			hpaEdge{to: a, cost: 10 * hpa.Grid.Cost(p.X, p.Y), inter: true})
+		transitions = append(transitions, hpaTransition{a, b})
+	}
+	// terrain the crossing at i goes through; on a weighted grid a run is
+	// cut wherever this changes, so each stretch of terrain gets its own
+	// transitions rather than the border being crossed at its ends only
+	terrain := func(i int) [2]int {
+		p := cell(i)
+		q := Position{p.X + across.X, p.Y + across.Y}
+		return [2]int{hpa.Grid.Cost(p.X, p.Y), hpa.Grid.Cost(q.X, q.Y)}
+	}
+	// find each maximal run of open cells along the border
+	runStart := -1
+	for i := 0; i <= n; i++ {
+		if i < n && open(i) &&
+			(runStart < 0 || terrain(i) == terrain(runStart)) {
+			if runStart < 0 {
+				runStart = i
+			}
+			continue
+		}
+		if runStart >= 0 {
+			runEnd := i - 1
+			if runEnd-runStart+1 < HPA_MAX_SINGLE_ENTRANCE {
+				add((runStart + runEnd) / 2)
+			} else {
+				add(runStart)
+				add(runEnd)
+			}
+			runStart = -1
+			// the cell that cut the run may start the next one
+			if i < n && open(i) {
+				runStart = i
+			}
+		}
+	}
+	hpa.borders[b] = transitions
+}
+
+// confine the local search to a cluster
+func (hpa *HPA) enterCluster(c Position) {
+	hpa.window.x0, hpa.window.y0, hpa.window.w, hpa.window.h =
+		hpa.clusterBounds(c)
+}
+
+// rebuildCluster recomputes the edges between the nodes of a cluster,
+// with one Dijkstra flood per node
+func (hpa *HPA) rebuildCluster(c Position) {
+	ids := hpa.clusterNodes[c]
+	for _, id := range ids {
+		n := hpa.nodes[id]
+		kept := n.edges[:0]
+		for _, e := range n.edges {
+			if e.inter {
+				kept = append(kept, e)
+			}
+		}
+		n.edges = kept
+	}
+	hpa.enterCluster(c)
+	for _, id := range ids {
+		hpa.linkWithinCluster(id, ids)
+	}
+}
+
+// linkWithinCluster adds edges from id to every node among ids that it can
+// reach without leaving its cluster (which must be the entered one)
+func (hpa *HPA) linkWithinCluster(id int, ids []int) {
+	n := hpa.nodes[id]
+	hpa.local.Dijkstra(hpa.window.toLocal(n.pos))
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		q := hpa.window.toLocal(hpa.nodes[other].pos)
+		if hpa.local.Reached(q.X, q.Y) {
+			n.edges = append(n.edges,
+				hpaEdge{to: other, cost: hpa.local.G[q.X][q.Y]})
+		}
+	}
+}
+
+// localPath searches from a to b without leaving cluster c, returning the
+// cells from a to b inclusive, or nil if b can't be reached that way
+func (hpa *HPA) localPath(c Position, a Position, b Position) []Position {
+	hpa.enterCluster(c)
+	path := hpa.local.Path(hpa.window.toLocal(a), hpa.window.toLocal(b))
+	hpa.Expanded += hpa.local.Expanded
+	if len(path) == 0 {
+		return nil
+	}
+	// local.Path runs from b back to a
+	forward := make([]Position, len(path))
+	for i, p := range path {
+		forward[len(path)-1-i] = hpa.window.toGrid(p)
+	}
+	return forward
+}
+
+// insertTemp adds a node for a query endpoint, linking it into its
+// cluster. out says whether to link it to the cluster's nodes (start) or
+// them to it (end). Returns the id and whether it was newly created
+func (hpa *HPA) insertTemp(p Position, out bool) (int, bool) {
+	_, existed := hpa.nodeAt[p]
+	id := hpa.acquire(p)
+	if existed {
+		return id, false
+	}
+	c := hpa.ClusterOf(p)
+	ids := hpa.clusterNodes[c]
+	hpa.enterCluster(c)
+	if out {
+		hpa.linkWithinCluster(id, ids)
+		return id, true
+	}
+	to := hpa.window.toLocal(p)
+	for _, other := range ids {
+		if other == id {
+			continue
+		}
+		from := hpa.window.toLocal(hpa.nodes[other].pos)
+		if path := hpa.local.Path(from, to); len(path) > 0 {
+			hpa.nodes[other].edges = append(hpa.nodes[other].edges,
+				hpaEdge{to: id, cost: hpa.local.G[to.X][to.Y]})
+		}
+	}
+	return id, true
+}
+
+// dropTemp undoes insertTemp
+func (hpa *HPA) dropTemp(id int) {
+	n := hpa.nodes[id]
+	for _, other := range hpa.clusterNodes[n.cluster] {
+		if other != id {
+			hpa.removeEdges(other, id, false)
+		}
+	}
+	hpa.release(id)
+}
+
+// Path returns the same format as PathComputer.Path (every cell from end
+// back to start, inclusive), or an empty slice if there is no path. The
+// path is near-optimal: optimal within each cluster, but constrained to
+// cross borders at transitions
+func (hpa *HPA) Path(start Position, end Position) []Position {
+	hpa.Expanded = 0
+	if !hpa.walkable(start) || !hpa.walkable(end) {
+		return []Position{}
+	}
+	if start == end {
+		return []Position{start}
+	}
+	// if both ends share a cluster, a path inside it is usually the answer
+	cs := hpa.ClusterOf(start)
+	if cs == hpa.ClusterOf(end) {
+		if forward := hpa.localPath(cs, start, end); forward != nil {
+			return reversed(forward)
+		}
+	}
+	sid, sTemp := hpa.insertTemp(start, true)
+	eid, eTemp := hpa.insertTemp(end, false)
+	hops := hpa.abstractPath(sid, eid)
+	// refine each hop: inter edges are a single step, the others a search
+	// within the cluster
+	forward := []Position{start}
+	refined := true
+	for i := 0; i+1 < len(hops); i++ {
+		a, b := hpa.nodes[hops[i]], hpa.nodes[hops[i+1]]
+		if a.cluster != b.cluster {
+			forward = append(forward, b.pos)
+			continue
+		}
+		leg := hpa.localPath(a.cluster, a.pos, b.pos)
+		if leg == nil {
+			refined = false
+			break
+		}
+		forward = append(forward, leg[1:]...)
+	}
+	if eTemp {
+		hpa.dropTemp(eid)
+	} else {
+		hpa.release(eid)
+	}
+	if sTemp {
+		hpa.dropTemp(sid)
+	} else {
+		hpa.release(sid)
+	}
+	if len(hops) == 0 || !refined {
+		return []Position{}
+	}
+	return reversed(forward)
+}
+
+func reversed(path []Position) []Position {
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// abstractPath is A* over the abstract graph, returning node ids from
+// start to end, or nil
+func (hpa *HPA) abstractPath(start int, end int) []int {
+	endPos := hpa.nodes[end].pos
+	g := map[int]int{start: 0}
+	from := map[int]int{start: -1}
+	closed := make(map[int]bool)
+	q := &hpaQueue{}
+	heap.Push(q, hpaQueueItem{start, hpa.Heuristic(hpa.nodes[start].pos, endPos)})
+	for q.Len() > 0 {
+		cur := heap.Pop(q).(hpaQueueItem).id
+		// we push rather than modify, so skip stale entries
+		if closed[cur] {
+			continue
+		}
+		closed[cur] = true
+		hpa.Expanded++
+		if cur == end {
+			ids := make([]int, 0)
+			for id := end; id != -1; id = from[id] {
+				ids = append(ids, id)
+			}
+			for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+				ids[i], ids[j] = ids[j], ids[i]
+			}
+			return ids
+		}
+		for _, e := range hpa.nodes[cur].edges {
+			if closed[e.to] {
+				continue
+			}
+			cost := g[cur] + e.cost
+			if already, ok := g[e.to]; ok && cost >= already {
+				continue
+			}
+			g[e.to] = cost
+			from[e.to] = cur
+			f := cost + hpa.Heuristic(hpa.nodes[e.to].pos, endPos)
+			heap.Push(q, hpaQueueItem{e.to, f})
+		}
+	}
+	return nil
+}
+
+type hpaQueueItem struct {
+	id int
+	f  int
+}
+
+// container/heap priority queue of abstract nodes, lowest f first
+type hpaQueue []hpaQueueItem
+
+func (q hpaQueue) Len() int            { return len(q) }
+func (q hpaQueue) Less(i, j int) bool  { return q[i].f < q[j].f }
+func (q hpaQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *hpaQueue) Push(x interface{}) { *q = append(*q, x.(hpaQueueItem)) }
+func (q *hpaQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// windowGrid is the part of a Grid inside a rectangle, in coordinates
+// relative to the rectangle's corner
+type windowGrid struct {
+	g   Grid
+	x0  int
+	y0  int
+	w   int
+	h   int
+	buf []Position
+}
+
+func (wg *windowGrid) toLocal(p Position) Position {
+	return Position{p.X - wg.x0, p.Y - wg.y0}
+}
+
+func (wg *windowGrid) toGrid(p Position) Position {
+	return Position{p.X + wg.x0, p.Y + wg.y0}
+}
+
+func (wg *windowGrid) InGrid(x int, y int) bool {
+	return x >= 0 && x < wg.w && y >= 0 && y < wg.h
+}
+
+func (wg *windowGrid) Passable(x int, y int) bool {
+	return wg.g.Passable(x+wg.x0, y+wg.y0)
+}
+
+func (wg *windowGrid) Cost(x int, y int) int {
+	return wg.g.Cost(x+wg.x0, y+wg.y0)
+}
+
+// Neighbors defers to the underlying grid (keeping its rules, e.g. about
+// corner-cutting) and drops anything outside the window
+func (wg *windowGrid) Neighbors(p Position, buf []Position) []Position {
+	wg.buf = wg.g.Neighbors(wg.toGrid(p), wg.buf[:0])
+	for _, n := range wg.buf {
+		n = wg.toLocal(n)
+		if wg.InGrid(n.X, n.Y) {
+			buf = append(buf, n)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,170 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func weightedPathCost(g Grid, path []Position) int {
+	cost := 0
+	for i := 0; i+1 < len(path); i++ {
+		cost += StepDistance(path[i], path[i+1]) *
+			g.Cost(path[i+1].X, path[i+1].Y)
+	}
+	return cost
+}
+
+// randomWeightedGrid is randomGrid with the open cells given costs 1-9,
+// in 5 x 5 patches (like terrain, rather than noise)
+func randomWeightedGrid(w int, h int, density float64, seed int64) *testGrid {
+	g := randomGrid(w, h, density, seed)
+	r := rand.New(rand.NewSource(seed))
+	patches := make(map[Position]byte)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			patch := Position{x / 5, y / 5}
+			if _, ok := patches[patch]; !ok {
+				patches[patch] = byte('1' + r.Intn(9))
+			}
+			if g.cells[x][y] != '#' {
+				g.cells[x][y] = patches[patch]
+			}
+		}
+	}
+	return g
+}
+
+// checkHPA compares HPA paths with optimal ones. HPA only crosses
+// borders at transitions, so short paths near a border can come out well
+// above optimal; overall the overhead should be small
+func checkHPA(t *testing.T, g *testGrid, hpa *HPA, r *rand.Rand, n int) {
+	pc := NewPathComputer(g, g.w, g.h)
+	hpaTotal, optimalTotal := 0, 0
+	for i := 0; i < n; i++ {
+		start := randomOpenCell(g, r)
+		end := randomOpenCell(g, r)
+		optimal := pc.Path(start, end)
+		path := hpa.Path(start, end)
+		if (len(optimal) == 0) != (len(path) == 0) {
+			t.Fatalf("%v->%v: A* found %d cells, HPA %d:\n%s",
+				start, end, len(optimal), len(path), g.String(path))
+		}
+		if len(path) == 0 {
+			continue
+		}
+		if path[0] != end || path[len(path)-1] != start {
+			t.Fatalf("HPA path runs %v->%v, expected %v->%v",
+				path[len(path)-1], path[0], start, end)
+		}
+		// paths run end to start, so check validity on the forward path
+		forward := make([]Position, len(path))
+		for i, p := range path {
+			forward[len(path)-1-i] = p
+		}
+		if !validPath(g, forward) {
+			t.Fatalf("HPA path has invalid steps:\n%s", g.String(path))
+		}
+		// forward cost is cost of entering each cell after start
+		hc := weightedPathCost(g, forward)
+		oc := weightedPathCost(g, reversed(optimal))
+		if hc < oc || float64(hc) > 3*float64(oc) {
+			t.Fatalf("%v->%v: HPA cost %d, optimal %d:\n%s",
+				start, end, hc, oc, g.String(path))
+		}
+		hpaTotal += hc
+		optimalTotal += oc
+	}
+	if float64(hpaTotal) > 1.15*float64(optimalTotal) {
+		t.Fatalf("HPA paths cost %d in total, optimal ones %d",
+			hpaTotal, optimalTotal)
+	}
+}
+
+func TestHPAObstacles(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		g := randomGrid(60, 45, 0.2, seed)
+		hpa := NewHPA(g, g.w, g.h, 10)
+		checkHPA(t, g, hpa, rand.New(rand.NewSource(seed)), 50)
+	}
+}
+
+func TestHPAWeighted(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		g := randomWeightedGrid(50, 50, 0.0, seed)
+		g.cutCorners = true
+		hpa := NewHPA(g, g.w, g.h, 8)
+		checkHPA(t, g, hpa, rand.New(rand.NewSource(seed)), 50)
+	}
+}
+
+func TestHPAUpdateCell(t *testing.T) {
+	g := randomGrid(40, 40, 0.15, 3)
+	hpa := NewHPA(g, g.w, g.h, 8)
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		x, y := r.Intn(g.w), r.Intn(g.h)
+		if g.cells[x][y] == '#' {
+			g.cells[x][y] = '.'
+		} else {
+			g.cells[x][y] = '#'
+		}
+		hpa.UpdateCell(x, y)
+	}
+	// an incrementally updated abstraction should give the same answers
+	// as one built from scratch
+	fresh := NewHPA(g, g.w, g.h, 8)
+	if hpa.Nodes() != fresh.Nodes() {
+		t.Fatalf("updated HPA has %d nodes, fresh one %d",
+			hpa.Nodes(), fresh.Nodes())
+	}
+	for i := 0; i < 100; i++ {
+		start := randomOpenCell(g, r)
+		end := randomOpenCell(g, r)
+		a := weightedPathCost(g, hpa.Path(start, end))
+		b := weightedPathCost(g, fresh.Path(start, end))
+		if a != b {
+			t.Fatalf("%v->%v: updated HPA cost %d, fresh %d", start, end, a, b)
+		}
+	}
+	checkHPA(t, g, hpa, r, 50)
+}
+
+// a cell blocked without UpdateCell leaves the abstraction stale; a leg
+// that can no longer be refined is no path rather than a panic
+func TestHPAStaleLeg(t *testing.T) {
+	g := randomGrid(30, 10, 0.0, 0)
+	hpa := NewHPA(g, g.w, g.h, 10)
+	for y := 0; y < g.h; y++ {
+		g.cells[15][y] = '#'
+	}
+	if path := hpa.Path(Position{2, 5}, Position{27, 5}); len(path) != 0 {
+		t.Fatalf("path through the wall:\n%s", g.String(path))
+	}
+}
+
+func BenchmarkHPABuild256(b *testing.B) {
+	g := randomWeightedGrid(256, 256, 0.1, 1)
+	for i := 0; i < b.N; i++ {
+		NewHPA(g, g.w, g.h, 16)
+	}
+}
+
+func BenchmarkHPAPath256(b *testing.B) {
+	g := randomWeightedGrid(256, 256, 0.1, 1)
+	hpa := NewHPA(g, g.w, g.h, 16)
+	r := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hpa.Path(randomOpenCell(g, r), randomOpenCell(g, r))
+	}
+}
+
+func BenchmarkAStarPath256Weighted(b *testing.B) {
+	g := randomWeightedGrid(256, 256, 0.1, 1)
+	pc := NewPathComputer(g, g.w, g.h)
+	r := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pc.Path(randomOpenCell(g, r), randomOpenCell(g, r))
+	}
+}
@@ -0,0 +1,230 @@
+package gridpath
+
+// Jump Point Search (Harabor & Grastien) over the same node arrays and
+// open heap as Path. Only uniform-cost grids are supported: Grid.Cost is
+// ignored, and only Grid.InGrid / Grid.Passable are consulted.
+//
+// Diagonal moves follow the same no-corner-cutting rule as
+// AppendNeighbors(..., false): a diagonal step is only allowed when both
+// of the cells flanking it are passable. Because of that rule the only
+// forced neighbors arise on straight moves, which keeps jump() simple.
+
+// walkable is InGrid && Passable
+func (pc *PathComputer) walkable(x int, y int) bool {
+	return pc.Grid.InGrid(x, y) && pc.Grid.Passable(x, y)
+}
+
+// JPSPath returns the same format as Path (every cell from end back to
+// start, inclusive), or an empty slice if there is no path
+func (pc *PathComputer) JPSPath(start Position, end Position) (path []Position) {
+	// clear the heap and bump N exactly as in Path
+	pc.OH.Clear()
+	pc.N += 2
+	pc.Expanded = 0
+
+	pc.WhichList[start.X][start.Y] = pc.N
+	pc.From[start.X][start.Y] = NOWHERE
+	pc.G[start.X][start.Y] = 0
+	pc.H[start.X][start.Y] = pc.Heuristic(start, end)
+	pc.OH.Add(start)
+	for pc.OH.Len() > 0 {
+		cur, err := pc.OH.Pop()
+		if err != nil {
+			return []Position{}
+		}
+		pc.WhichList[cur.X][cur.Y] = pc.N + 1
+		pc.Expanded++
+		if cur.X == end.X && cur.Y == end.Y {
+			return pc.expandJumps(cur)
+		}
+		// for each pruned neighbor direction, jump as far as we can
+		pc.buf = pc.prunedNeighbors(cur, pc.buf[:0])
+		for _, n := range pc.buf {
+			jp, ok := pc.jump(n.X, n.Y, n.X-cur.X, n.Y-cur.Y, end)
+			if !ok {
+				continue
+			}
+			x, y := jp.X, jp.Y
+			if pc.WhichList[x][y] == pc.N+1 {
+				continue
+			}
+			g := pc.G[cur.X][cur.Y] + OctileDistance(cur, jp)
+			if pc.WhichList[x][y] != pc.N {
+				pc.From[x][y] = cur
+				pc.G[x][y] = g
+				pc.H[x][y] = pc.Heuristic(jp, end)
+				pc.WhichList[x][y] = pc.N
+				pc.OH.Add(jp)
+			} else if g < pc.G[x][y] {
+				pc.From[x][y] = cur
+				pc.OH.Modify(pc.HeapIX[x][y], g)
+			}
+		}
+	}
+	return []Position{}
+}
+
+// OctileDistance is the cost of the cheapest 8-connected walk between two
+// cells on an open uniform grid, in the same units as StepDistance
+func OctileDistance(p1 Position, p2 Position) int {
+	dx := p1.X - p2.X
+	if dx < 0 {
+		dx *= -1
+	}
+	dy := p1.Y - p2.Y
+	if dy < 0 {
+		dy *= -1
+	}
+	if dx < dy {
+		return 14*dx + 10*(dy-dx)
+	}
+	return 14*dy + 10*(dx-dy)
+}
+
+func sign(x int) int {
+	if x > 0 {
+		return 1
+	} else if x < 0 {
+		return -1
+	}
+	return 0
+}
+
+// prunedNeighbors appends the first cell in each direction worth jumping
+// toward from p, given the direction we arrived at p from
+func (pc *PathComputer) prunedNeighbors(p Position, buf []Position) []Position {
+	from := pc.From[p.X][p.Y]
+	// the start node has no direction of travel: consider every neighbor
+	if from == NOWHERE {
+		return AppendNeighbors(pc.Grid, p, buf, false)
+	}
+	x, y := p.X, p.Y
+	dx, dy := sign(x-from.X), sign(y-from.Y)
+	if dx != 0 && dy != 0 {
+		// diagonal: the two straight components, and the diagonal itself
+		// if neither is blocked
+		canX := pc.walkable(x+dx, y)
+		canY := pc.walkable(x, y+dy)
+		if canY {
+			buf = append(buf, Position{x, y + dy})
+		}
+		if canX {
+			buf = append(buf, Position{x + dx, y})
+		}
+		if canX && canY {
+			buf = append(buf, Position{x + dx, y + dy})
+		}
+	} else if dx != 0 {
+		// horizontal: straight on, plus the cells above and below and
+		// the diagonals leading past them. With corners uncuttable, a
+		// turn can only be forced here, so we always consider them
+		next := pc.walkable(x+dx, y)
+		up := pc.walkable(x, y+1)
+		down := pc.walkable(x, y-1)
+		if next {
+			buf = append(buf, Position{x + dx, y})
+			if up {
+				buf = append(buf, Position{x + dx, y + 1})
+			}
+			if down {
+				buf = append(buf, Position{x + dx, y - 1})
+			}
+		}
+		if up {
+			buf = append(buf, Position{x, y + 1})
+		}
+		if down {
+			buf = append(buf, Position{x, y - 1})
+		}
+	} else {
+		// vertical: as above, rotated
+		next := pc.walkable(x, y+dy)
+		right := pc.walkable(x+1, y)
+		left := pc.walkable(x-1, y)
+		if next {
+			buf = append(buf, Position{x, y + dy})
+			if right {
+				buf = append(buf, Position{x + 1, y + dy})
+			}
+			if left {
+				buf = append(buf, Position{x - 1, y + dy})
+			}
+		}
+		if right {
+			buf = append(buf, Position{x + 1, y})
+		}
+		if left {
+			buf = append(buf, Position{x - 1, y})
+		}
+	}
+	return buf
+}
+
+// jump walks from x, y in direction dx, dy (x, y being the first step
+// taken) until it finds a jump point: the end, a cell with a forced
+// neighbor, or (moving diagonally) a cell from which a straight jump
+// finds one. Returns false if it runs into a wall or the edge of the grid
+func (pc *PathComputer) jump(
+	x int, y int, dx int, dy int, end Position) (Position, bool) {
+
+	for {
+		if !pc.walkable(x, y) {
+			return NOWHERE, false
+		}
+		if x == end.X && y == end.Y {
+			return Position{x, y}, true
+		}
+		if dx != 0 && dy != 0 {
+			// diagonal: we're a jump point if either straight component
+			// leads to one
+			if _, ok := pc.jump(x+dx, y, dx, 0, end); ok {
+				return Position{x, y}, true
+			}
+			if _, ok := pc.jump(x, y+dy, 0, dy, end); ok {
+				return Position{x, y}, true
+			}
+		} else if dx != 0 {
+			// horizontal: forced neighbor if a cell above/below is open
+			// but the one behind it is blocked (we couldn't have cut the
+			// corner to get there)
+			if (pc.walkable(x, y+1) && !pc.walkable(x-dx, y+1)) ||
+				(pc.walkable(x, y-1) && !pc.walkable(x-dx, y-1)) {
+				return Position{x, y}, true
+			}
+		} else {
+			if (pc.walkable(x+1, y) && !pc.walkable(x+1, y-dy)) ||
+				(pc.walkable(x-1, y) && !pc.walkable(x-1, y-dy)) {
+				return Position{x, y}, true
+			}
+		}
+		// don't cut corners on the next diagonal step
+		if dx != 0 && dy != 0 &&
+			(!pc.walkable(x+dx, y) || !pc.walkable(x, y+dy)) {
+			return NOWHERE, false
+		}
+		x += dx
+		y += dy
+	}
+}
+
+// expandJumps follows From back from the end, filling in the straight or
+// diagonal run of cells between each pair of jump points
+func (pc *PathComputer) expandJumps(end Position) []Position {
+	path := make([]Position, 0)
+	cur := end
+	for cur != NOWHERE {
+		from := pc.From[cur.X][cur.Y]
+		path = append(path, cur)
+		if from == NOWHERE {
+			break
+		}
+		dx, dy := sign(from.X-cur.X), sign(from.Y-cur.Y)
+		p := Position{cur.X + dx, cur.Y + dy}
+		for p != from {
+			path = append(path, p)
+			p = Position{p.X + dx, p.Y + dy}
+		}
+		cur = from
+	}
+	return path
+}
@@ -0,0 +1,128 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomGrid builds a w x h grid with roughly density of its cells blocked
+func randomGrid(w int, h int, density float64, seed int64) *testGrid {
+	r := rand.New(rand.NewSource(seed))
+	rows := make([]string, h)
+	for y := 0; y < h; y++ {
+		row := make([]byte, w)
+		for x := 0; x < w; x++ {
+			if r.Float64() < density {
+				row[x] = '#'
+			} else {
+				row[x] = '.'
+			}
+		}
+		rows[y] = string(row)
+	}
+	return newTestGrid(rows...)
+}
+
+func randomOpenCell(g *testGrid, r *rand.Rand) Position {
+	for {
+		p := Position{r.Intn(g.w), r.Intn(g.h)}
+		if g.Passable(p.X, p.Y) {
+			return p
+		}
+	}
+}
+
+func pathCost(path []Position) int {
+	cost := 0
+	for i := 0; i+1 < len(path); i++ {
+		cost += StepDistance(path[i], path[i+1])
+	}
+	return cost
+}
+
+func TestJPSMatchesAStar(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		g := randomGrid(32, 32, 0.25, seed)
+		pc := NewPathComputer(g, g.w, g.h)
+		r := rand.New(rand.NewSource(seed))
+		for i := 0; i < 50; i++ {
+			start := randomOpenCell(g, r)
+			end := randomOpenCell(g, r)
+			astar := pc.Path(start, end)
+			jps := pc.JPSPath(start, end)
+			if (len(astar) == 0) != (len(jps) == 0) {
+				t.Fatalf("seed %d %v->%v: A* found %d cells, JPS %d",
+					seed, start, end, len(astar), len(jps))
+			}
+			if len(jps) == 0 {
+				continue
+			}
+			if jps[0] != end || jps[len(jps)-1] != start {
+				t.Fatalf("seed %d: JPS path runs %v->%v, expected %v->%v",
+					seed, jps[0], jps[len(jps)-1], end, start)
+			}
+			if !validPath(g, jps) {
+				t.Fatalf("seed %d: JPS path has invalid steps:\n%s",
+					seed, g.String(jps))
+			}
+			if pathCost(astar) != pathCost(jps) {
+				t.Fatalf("seed %d %v->%v: A* cost %d, JPS cost %d\n%s\n%s",
+					seed, start, end, pathCost(astar), pathCost(jps),
+					g.String(astar), g.String(jps))
+			}
+		}
+	}
+}
+
+func TestJPSUnreachable(t *testing.T) {
+	g := newTestGrid(
+		"..#..",
+		"..#..",
+		"..#..")
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.JPSPath(Position{0, 0}, Position{4, 2})
+	if path == nil || len(path) != 0 {
+		t.Fatalf("expected an empty path, got %#v", path)
+	}
+}
+
+func TestJPSNoCornerCutting(t *testing.T) {
+	g := newTestGrid(
+		"....",
+		".#..",
+		"....")
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.JPSPath(Position{0, 0}, Position{2, 2})
+	if !validPath(g, path) {
+		t.Fatalf("JPS cut a corner:\n%s", g.String(path))
+	}
+}
+
+func benchmarkSearch(b *testing.B, dim int, density float64, jps bool) {
+	g := randomGrid(dim, dim, density, 1)
+	pc := NewPathComputer(g, g.w, g.h)
+	r := rand.New(rand.NewSource(1))
+	pairs := make([][2]Position, 256)
+	for i := range pairs {
+		pairs[i] = [2]Position{randomOpenCell(g, r), randomOpenCell(g, r)}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pair := pairs[i%len(pairs)]
+		if jps {
+			pc.JPSPath(pair[0], pair[1])
+		} else {
+			pc.Path(pair[0], pair[1])
+		}
+	}
+}
+
+// 42 x 42 is the size of the diffusion_pathfinding grid
+func BenchmarkAStar42Open(b *testing.B)   { benchmarkSearch(b, 42, 0.0, false) }
+func BenchmarkJPS42Open(b *testing.B)     { benchmarkSearch(b, 42, 0.0, true) }
+func BenchmarkAStar42Sparse(b *testing.B) { benchmarkSearch(b, 42, 0.1, false) }
+func BenchmarkJPS42Sparse(b *testing.B)   { benchmarkSearch(b, 42, 0.1, true) }
+func BenchmarkAStar256Open(b *testing.B)  { benchmarkSearch(b, 256, 0.0, false) }
+func BenchmarkJPS256Open(b *testing.B)    { benchmarkSearch(b, 256, 0.0, true) }
+func BenchmarkAStar256Dense(b *testing.B) { benchmarkSearch(b, 256, 0.3, false) }
+func BenchmarkJPS256Dense(b *testing.B)   { benchmarkSearch(b, 256, 0.3, true) }
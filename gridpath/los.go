@@ -0,0 +1,154 @@
+package gridpath
+
+import (
+	"math"
+)
+
+// walkLine visits, in order, each cell the straight line from the centre of
+// a to the centre of b passes through, with the length of the line inside
+// it (in cells). Where the line passes exactly through a corner it steps
+// diagonally, first calling corner with the cells it steps between. Either
+// callback can return false to stop the walk, in which case walkLine
+// returns false
+func walkLine(a Position, b Position,
+	visit func(p Position, length float64) bool,
+	corner func(from Position, to Position) bool) bool {
+
+	dx, dy := b.X-a.X, b.Y-a.Y
+	sx, sy := 1, 1
+	adx, ady := dx, dy
+	if dx < 0 {
+		sx, adx = -1, -dx
+	}
+	if dy < 0 {
+		sy, ady = -1, -dy
+	}
+	length := math.Sqrt(float64(dx*dx + dy*dy))
+	// kx, ky: the x and y cell boundaries crossed so far. The line crosses
+	// its k'th x boundary at t = (2k+1) / 2|dx| along it, and likewise for
+	// y, so which comes next is decided in integers
+	cur := a
+	kx, ky := 0, 0
+	t := 0.0
+	for {
+		moreX, moreY := kx < adx, ky < ady
+		var next float64
+		stepX, stepY := false, false
+		switch {
+		case !moreX && !moreY:
+			next = 1
+		case moreX && moreY:
+			cx, cy := (2*kx+1)*ady, (2*ky+1)*adx
+			stepX, stepY = cx <= cy, cy <= cx
+		default:
+			stepX, stepY = moreX, moreY
+		}
+		if stepX {
+			next = float64(2*kx+1) / float64(2*adx)
+		} else if stepY {
+			next = float64(2*ky+1) / float64(2*ady)
+		}
+		if !visit(cur, (next-t)*length) {
+			return false
+		}
+		if !stepX && !stepY {
+			return true
+		}
+		t = next
+		to := cur
+		if stepX {
+			to.X += sx
+			kx++
+		}
+		if stepY {
+			to.Y += sy
+			ky++
+		}
+		if stepX && stepY && corner != nil && !corner(cur, to) {
+			return false
+		}
+		cur = to
+	}
+}
+
+// LineCells returns the cells the straight line from the centre of a to the
+// centre of b passes through, from a to b inclusive. Consecutive cells are
+// always neighbours (diagonal where the line passes through a corner)
+func LineCells(a Position, b Position) []Position {
+	var cells []Position
+	walkLine(a, b, func(p Position, _ float64) bool {
+		cells = append(cells, p)
+		return true
+	}, nil)
+	return cells
+}
+
+// PathCells fills in the cells along each straight segment of a path of
+// waypoints (such as one from StringPull), so that consecutive cells are
+// neighbours again
+func PathCells(path []Position) []Position {
+	if len(path) == 0 {
+		return nil
+	}
+	cells := []Position{path[0]}
+	for i := 0; i+1 < len(path); i++ {
+		cells = append(cells, LineCells(path[i], path[i+1])[1:]...)
+	}
+	return cells
+}
+
+// LineCost integrates the terrain cost along the straight line from the
+// centre of a to the centre of b: each cell it passes through adds the
+// length of the line inside the cell times the cell's Cost, scaled so a
+// cell's width is 10 (as in StepDistance). ok is false if the line passes
+// through an impassable cell, or squeezes diagonally between two cells
+// either of which is impassable (the same corners AppendNeighbors won't
+// cut)
+func LineCost(g Grid, a Position, b Position) (cost int, ok bool) {
+	total := 0.0
+	ok = walkLine(a, b, func(p Position, length float64) bool {
+		if !g.InGrid(p.X, p.Y) || !g.Passable(p.X, p.Y) {
+			return false
+		}
+		total += length * float64(g.Cost(p.X, p.Y))
+		return true
+	}, func(from Position, to Position) bool {
+		return g.Passable(to.X, from.Y) && g.Passable(from.X, to.Y)
+	})
+	return int(math.Round(total * 10)), ok
+}
+
+// LineOfSight reports whether the straight line between the centres of a
+// and b stays clear of impassable cells (see LineCost)
+func LineOfSight(g Grid, a Position, b Position) bool {
+	_, ok := LineCost(g, a, b)
+	return ok
+}
+
+// StringPull removes the waypoints of path (in either order) which can be
+// skipped by walking straight from an earlier one to a later one. A
+// shortcut is only taken if the line is clear and, on a weighted grid, no
+// more costly (by LineCost) than the steps it replaces, so paths still
+// skirt around expensive terrain. The ends of the path are always kept
+func StringPull(g Grid, path []Position) []Position {
+	if len(path) < 3 {
+		return append([]Position(nil), path...)
+	}
+	pulled := []Position{path[0]}
+	for i := 0; i < len(path)-1; {
+		best := i + 1
+		along, _ := LineCost(g, path[i], path[i+1])
+		for k := i + 2; k < len(path); k++ {
+			step, _ := LineCost(g, path[k-1], path[k])
+			along += step
+			direct, ok := LineCost(g, path[i], path[k])
+			if !ok || direct > along {
+				break
+			}
+			best = k
+		}
+		pulled = append(pulled, path[best])
+		i = best
+	}
+	return pulled
+}
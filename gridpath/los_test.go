@@ -0,0 +1,155 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLineCells(t *testing.T) {
+	cases := []struct {
+		a, b Position
+		want []Position
+	}{
+		{Position{0, 0}, Position{0, 0}, []Position{{0, 0}}},
+		{Position{0, 0}, Position{3, 0}, []Position{{0, 0}, {1, 0}, {2, 0}, {3, 0}}},
+		{Position{2, 2}, Position{0, 0}, []Position{{2, 2}, {1, 1}, {0, 0}}},
+		{Position{0, 0}, Position{2, 1}, []Position{{0, 0}, {1, 0}, {1, 1}, {2, 1}}},
+	}
+	for _, c := range cases {
+		got := LineCells(c.a, c.b)
+		if len(got) != len(c.want) {
+			t.Fatalf("%v -> %v: got %v, want %v", c.a, c.b, got, c.want)
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Fatalf("%v -> %v: got %v, want %v", c.a, c.b, got, c.want)
+			}
+		}
+	}
+}
+
+func TestLineCost(t *testing.T) {
+	g := newTestGrid(
+		"....",
+		".#..",
+		"....",
+		"5555",
+	)
+	if c, ok := LineCost(g, Position{0, 0}, Position{3, 0}); !ok || c != 30 {
+		t.Fatalf("straight line: %d, %v", c, ok)
+	}
+	if c, ok := LineCost(g, Position{0, 0}, Position{2, 2}); ok {
+		t.Fatalf("line through the obstacle is clear (cost %d)", c)
+	}
+	// squeezing past the obstacle's corner
+	if LineOfSight(g, Position{0, 1}, Position{1, 0}) ||
+		LineOfSight(g, Position{1, 0}, Position{2, 1}) {
+		t.Fatal("line cuts the obstacle's corner")
+	}
+	if !LineOfSight(g, Position{2, 0}, Position{3, 1}) {
+		t.Fatal("clear diagonal is blocked")
+	}
+	// half of each end cell: 5 + 10*2 + 5 along the cost-5 row, and the
+	// diagonal step is sqrt(2) * 10
+	if c, _ := LineCost(g, Position{0, 3}, Position{3, 3}); c != 150 {
+		t.Fatalf("weighted line costs %d, want 150", c)
+	}
+	if c, _ := LineCost(g, Position{2, 0}, Position{3, 1}); c != 14 {
+		t.Fatalf("diagonal costs %d, want 14", c)
+	}
+}
+
+func TestStringPullOpen(t *testing.T) {
+	g := randomGrid(16, 16, 0, 0)
+	pc := NewPathComputer(g, 16, 16)
+	path := pc.Path(Position{0, 0}, Position{15, 6})
+	pulled := StringPull(g, path)
+	if len(pulled) != 2 || pulled[0] != path[0] ||
+		pulled[1] != path[len(path)-1] {
+		t.Fatalf("open-grid path not pulled straight: %v", pulled)
+	}
+}
+
+func TestStringPullAroundWall(t *testing.T) {
+	g := newTestGrid(
+		"........",
+		"........",
+		"####....",
+		"........",
+		"........",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 4}, Position{0, 0})
+	pulled := StringPull(g, path)
+	if len(pulled) < 3 {
+		t.Fatalf("path through the wall: %v", pulled)
+	}
+	for i := 0; i+1 < len(pulled); i++ {
+		if !LineOfSight(g, pulled[i], pulled[i+1]) {
+			t.Fatalf("no line of sight between %v and %v", pulled[i], pulled[i+1])
+		}
+	}
+	if len(pulled) >= len(path) {
+		t.Fatalf("nothing was pulled: %d of %d waypoints kept",
+			len(pulled), len(path))
+	}
+}
+
+func TestStringPullKeepsToCheapTerrain(t *testing.T) {
+	// the path curves around the expensive block; cutting across it would
+	// be shorter but costlier
+	g := newTestGrid(
+		"..........",
+		".99999999.",
+		".99999999.",
+		"..........",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.Path(Position{0, 1}, Position{9, 2})
+	pulled := StringPull(g, path)
+	for _, p := range PathCells(pulled) {
+		if g.Cost(p.X, p.Y) == 9 {
+			t.Fatalf("pulled path crosses expensive terrain at %v: %v",
+				p, pulled)
+		}
+	}
+}
+
+func TestStringPullRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for seed := int64(0); seed < 20; seed++ {
+		g := randomGrid(32, 32, 0.25, seed)
+		pc := NewPathComputer(g, g.w, g.h)
+		for i := 0; i < 20; i++ {
+			a, b := randomOpenCell(g, r), randomOpenCell(g, r)
+			path := pc.Path(a, b)
+			if len(path) == 0 {
+				continue
+			}
+			pulled := StringPull(g, path)
+			if pulled[0] != path[0] || pulled[len(pulled)-1] != path[len(path)-1] {
+				t.Fatalf("pulled path changed its ends: %v", pulled)
+			}
+			for j := 0; j+1 < len(pulled); j++ {
+				if !LineOfSight(g, pulled[j], pulled[j+1]) {
+					t.Fatalf("seed %d: no line of sight between %v and %v",
+						seed, pulled[j], pulled[j+1])
+				}
+			}
+		}
+	}
+}
+
+func TestPathCells(t *testing.T) {
+	path := []Position{{0, 0}, {3, 0}, {3, 2}}
+	cells := PathCells(path)
+	want := []Position{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {3, 1}, {3, 2}}
+	if len(cells) != len(want) {
+		t.Fatalf("got %v, want %v", cells, want)
+	}
+	for i := range want {
+		if cells[i] != want[i] {
+			t.Fatalf("got %v, want %v", cells, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package gridpath
+
+import (
+	"fmt"
+)
+
+type Position struct {
+	X int
+	Y int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("[%d, %d]", p.X, p.Y)
+}
+
+// special value used for the "From" of the start node
+var NOWHERE = Position{-1, -1}
@@ -0,0 +1,135 @@
+package gridpath
+
+import (
+	"errors"
+	"fmt"
+)
+
+// why a path query failed. Queries return them wrapped in a PathError, so
+// check with errors.Is
+var (
+	ErrOutOfBounds  = errors.New("endpoint is off the grid")
+	ErrStartBlocked = errors.New("start is blocked")
+	ErrEndBlocked   = errors.New("goal is blocked")
+	ErrUnreachable  = errors.New("goal can't be reached from start")
+)
+
+// PathError is the error returned by a failed path query
+type PathError struct {
+	Start Position
+	End   Position
+	Err   error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("path %v -> %v: %v", e.Start, e.End, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// PathResult is the answer to a path query.
+//
+// Path:		cells from start to goal inclusive (waypoints, for any-angle searches)
+// Cost:		total cost of the path, in the search's own units (see Heuristic)
+// Expanded:	nodes the search closed on the way
+type PathResult struct {
+	Path     []Position
+	Cost     int
+	Expanded int
+}
+
+// which search a PathComputer's Find runs
+type Algorithm int
+
+const (
+	ASTAR Algorithm = iota
+	JPS
+	THETA
+)
+
+// CheckEndpoints returns the PathError for a query from start to end that
+// couldn't even begin, or nil
+func CheckEndpoints(g Grid, start Position, end Position) error {
+	fail := func(err error) error {
+		return &PathError{Start: start, End: end, Err: err}
+	}
+	if !g.InGrid(start.X, start.Y) || !g.InGrid(end.X, end.Y) {
+		return fail(ErrOutOfBounds)
+	}
+	if !g.Passable(start.X, start.Y) {
+		return fail(ErrStartBlocked)
+	}
+	if !g.Passable(end.X, end.Y) {
+		return fail(ErrEndBlocked)
+	}
+	return nil
+}
+
+// PathCost is the cost of walking path (start first) cell by cell: the
+// StepDistance of each step times the Cost of the cell it steps into
+func PathCost(g Grid, path []Position) int {
+	cost := 0
+	for i := 0; i+1 < len(path); i++ {
+		cost += StepDistance(path[i], path[i+1]) *
+			g.Cost(path[i+1].X, path[i+1].Y)
+	}
+	return cost
+}
+
+// Find searches from start to end with the given algorithm. Unlike Path,
+// JPSPath and ThetaPath, the path it returns runs from start to end
+func (pc *PathComputer) Find(
+	start Position, end Position, alg Algorithm) (PathResult, error) {
+	if err := CheckEndpoints(pc.Grid, start, end); err != nil {
+		return PathResult{}, err
+	}
+	var path []Position
+	switch alg {
+	case JPS:
+		path = pc.JPSPath(start, end)
+	case THETA:
+		path = pc.ThetaPath(start, end)
+	default:
+		path = pc.Path(start, end)
+	}
+	res := PathResult{Expanded: pc.Expanded}
+	if len(path) == 0 {
+		return res, &PathError{Start: start, End: end, Err: ErrUnreachable}
+	}
+	res.Path = reversed(path)
+	res.Cost = pc.G[end.X][end.Y]
+	return res, nil
+}
+
+// Find searches from start to end, returning the path from start to end
+func (hpa *HPA) Find(start Position, end Position) (PathResult, error) {
+	if err := CheckEndpoints(hpa.Grid, start, end); err != nil {
+		return PathResult{}, err
+	}
+	path := hpa.Path(start, end)
+	res := PathResult{Expanded: hpa.Expanded}
+	if len(path) == 0 {
+		return res, &PathError{Start: start, End: end, Err: ErrUnreachable}
+	}
+	res.Path = reversed(path)
+	res.Cost = PathCost(hpa.Grid, res.Path)
+	return res, nil
+}
+
+// Find returns the planned path from start to the goal, with the nodes
+// expanded by the last Compute (so call Compute first)
+func (ds *DStarLite) Find() (PathResult, error) {
+	if err := CheckEndpoints(ds.Grid, ds.start, ds.goal); err != nil {
+		return PathResult{}, err
+	}
+	path := ds.Path()
+	res := PathResult{Expanded: ds.Expanded}
+	if len(path) == 0 {
+		return res, &PathError{Start: ds.start, End: ds.goal, Err: ErrUnreachable}
+	}
+	res.Path = reversed(path)
+	res.Cost = ds.gOf(ds.start)
+	return res, nil
+}
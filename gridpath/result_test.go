@@ -0,0 +1,95 @@
+package gridpath
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindErrors(t *testing.T) {
+	g := newTestGrid(
+		"..#..",
+		"..#..",
+		"..#.#",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	cases := []struct {
+		start, end Position
+		want       error
+	}{
+		{Position{0, 0}, Position{5, 0}, ErrOutOfBounds},
+		{Position{-1, 0}, Position{1, 0}, ErrOutOfBounds},
+		{Position{2, 0}, Position{0, 0}, ErrStartBlocked},
+		{Position{0, 0}, Position{4, 2}, ErrEndBlocked},
+		{Position{0, 0}, Position{4, 0}, ErrUnreachable},
+	}
+	for _, c := range cases {
+		for _, alg := range []Algorithm{ASTAR, JPS, THETA} {
+			_, err := pc.Find(c.start, c.end, alg)
+			if !errors.Is(err, c.want) {
+				t.Fatalf("%v -> %v (alg %d): got %v, want %v",
+					c.start, c.end, alg, err, c.want)
+			}
+			var perr *PathError
+			if !errors.As(err, &perr) || perr.Start != c.start || perr.End != c.end {
+				t.Fatalf("%v -> %v: error doesn't carry the query: %#v",
+					c.start, c.end, err)
+			}
+		}
+	}
+}
+
+func TestFindStartIsEnd(t *testing.T) {
+	g := randomGrid(5, 5, 0, 0)
+	pc := NewPathComputer(g, g.w, g.h)
+	res, err := pc.Find(Position{2, 2}, Position{2, 2}, ASTAR)
+	if err != nil || len(res.Path) != 1 || res.Cost != 0 {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+}
+
+func TestFindOrderAndCost(t *testing.T) {
+	g := randomWeightedGrid(32, 32, 0.1, 1)
+	start, end := Position{0, 0}, Position{31, 31}
+	g.cells[0][0], g.cells[31][31] = '.', '.'
+	pc := NewPathComputer(g, g.w, g.h)
+	hpa := NewHPA(g, g.w, g.h, 8)
+	ds := NewDStarLite(g, g.w, g.h)
+	check := func(name string, res PathResult, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if res.Path[0] != start || res.Path[len(res.Path)-1] != end {
+			t.Fatalf("%s: path runs %v -> %v", name,
+				res.Path[0], res.Path[len(res.Path)-1])
+		}
+		if res.Expanded == 0 {
+			t.Fatalf("%s: no nodes expanded", name)
+		}
+	}
+	res, err := pc.Find(start, end, ASTAR)
+	check("A*", res, err)
+	if res.Cost != PathCost(g, res.Path) {
+		t.Fatalf("A* cost %d, path costs %d", res.Cost, PathCost(g, res.Path))
+	}
+	astar := res.Cost
+	for _, a := range []struct {
+		name string
+		alg  Algorithm
+	}{{"JPS", JPS}, {"Theta*", THETA}} {
+		res, err := pc.Find(start, end, a.alg)
+		check(a.name, res, err)
+	}
+	res, err = hpa.Find(start, end)
+	check("HPA*", res, err)
+	if res.Cost != PathCost(g, res.Path) || res.Cost < astar {
+		t.Fatalf("HPA* cost %d, A* %d", res.Cost, astar)
+	}
+	ds.Reset(start, end)
+	ds.Compute()
+	res, err = ds.Find()
+	check("D* Lite", res, err)
+	if res.Cost != astar {
+		t.Fatalf("D* Lite cost %d, A* %d", res.Cost, astar)
+	}
+}
@@ -0,0 +1,80 @@
+package gridpath
+
+// Theta* (Nash, Daniel, Koenig & Felner): A* over the same node arrays and
+// open heap as Path, except that a node's parent may be any cell it can
+// see rather than only a grid neighbour, so the paths it finds are made of
+// straight segments at any angle.
+//
+// Every edge, neighbour step or shortcut, is costed by LineCost, so on a
+// weighted grid the terrain under each segment is integrated along it and
+// a shortcut is only taken when it's no more costly than going through the
+// neighbour. (Lazy Theta* defers line-of-sight checks until expansion, but
+// on a weighted grid the line has to be walked to cost it anyway, so there
+// is nothing to gain by deferring.)
+
+// ThetaPath returns the waypoints of an any-angle path from end back to
+// start (inclusive), each in line of sight of the next, or an empty slice
+// if there is no path. Use PathCells for every cell along the way. The
+// search always uses EuclideanHeuristic, since e.g. ManhattanHeuristic
+// overestimates the cost of a straight diagonal segment
+func (pc *PathComputer) ThetaPath(start Position, end Position) (path []Position) {
+	// clear the heap and bump N exactly as in Path
+	pc.OH.Clear()
+	pc.N += 2
+	pc.Expanded = 0
+
+	pc.WhichList[start.X][start.Y] = pc.N
+	pc.From[start.X][start.Y] = NOWHERE
+	pc.G[start.X][start.Y] = 0
+	pc.H[start.X][start.Y] = EuclideanHeuristic(start, end)
+	pc.OH.Add(start)
+	for pc.OH.Len() > 0 {
+		cur, err := pc.OH.Pop()
+		if err != nil {
+			return []Position{}
+		}
+		pc.WhichList[cur.X][cur.Y] = pc.N + 1
+		pc.Expanded++
+		if cur.X == end.X && cur.Y == end.Y {
+			path = make([]Position, 0)
+			for cur != NOWHERE {
+				path = append(path, cur)
+				cur = pc.From[cur.X][cur.Y]
+			}
+			return path
+		}
+		parent := pc.From[cur.X][cur.Y]
+		pc.buf = pc.Grid.Neighbors(cur, pc.buf[:0])
+		for _, n := range pc.buf {
+			x, y := n.X, n.Y
+			// through cur, as A* would
+			from := cur
+			step, _ := LineCost(pc.Grid, cur, n)
+			g := pc.G[cur.X][cur.Y] + step
+			// or straight from cur's parent, if it can see n
+			if parent != NOWHERE {
+				c, ok := LineCost(pc.Grid, parent, n)
+				if ok && pc.G[parent.X][parent.Y]+c <= g {
+					from = parent
+					g = pc.G[parent.X][parent.Y] + c
+				}
+			}
+			closed := pc.WhichList[x][y] == pc.N+1
+			if closed && g >= pc.G[x][y] {
+				continue
+			}
+			open := pc.WhichList[x][y] == pc.N
+			if !open {
+				pc.From[x][y] = from
+				pc.G[x][y] = g
+				pc.H[x][y] = EuclideanHeuristic(n, end)
+				pc.WhichList[x][y] = pc.N
+				pc.OH.Add(n)
+			} else if g < pc.G[x][y] {
+				pc.From[x][y] = from
+				pc.OH.Modify(pc.HeapIX[x][y], g)
+			}
+		}
+	}
+	return []Position{}
+}
@@ -0,0 +1,140 @@
+package gridpath
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the cost of a path of waypoints, each segment costed by LineCost (so
+// grid paths and any-angle paths can be compared)
+func lineCostOf(g Grid, path []Position) int {
+	cost := 0
+	for i := 0; i+1 < len(path); i++ {
+		c, _ := LineCost(g, path[i], path[i+1])
+		cost += c
+	}
+	return cost
+}
+
+func checkThetaPath(t *testing.T, g Grid, path []Position,
+	start Position, end Position) {
+	t.Helper()
+	if path[0] != end || path[len(path)-1] != start {
+		t.Fatalf("path runs %v -> %v, want %v -> %v",
+			path[0], path[len(path)-1], end, start)
+	}
+	for i := 0; i+1 < len(path); i++ {
+		if !LineOfSight(g, path[i], path[i+1]) {
+			t.Fatalf("no line of sight between %v and %v", path[i], path[i+1])
+		}
+	}
+}
+
+func TestThetaPathOpen(t *testing.T) {
+	g := randomGrid(20, 20, 0, 0)
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.ThetaPath(Position{0, 0}, Position{19, 7})
+	if len(path) != 2 {
+		t.Fatalf("open grid path has %d waypoints: %v", len(path), path)
+	}
+}
+
+func TestThetaPathAroundWall(t *testing.T) {
+	g := newTestGrid(
+		"..........",
+		"..........",
+		"######....",
+		"..........",
+		"..........",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	start, end := Position{0, 4}, Position{0, 0}
+	path := pc.ThetaPath(start, end)
+	checkThetaPath(t, g, path, start, end)
+	// down to the end of the wall, past it and back up
+	if len(path) > 4 {
+		t.Fatalf("expected at most two turns around the wall: %v", path)
+	}
+}
+
+func TestThetaPathUnreachable(t *testing.T) {
+	g := newTestGrid(
+		"..#..",
+		"..#..",
+		"..#..",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	path := pc.ThetaPath(Position{0, 0}, Position{4, 0})
+	if path == nil || len(path) != 0 {
+		t.Fatalf("expected an empty path, got %#v", path)
+	}
+}
+
+func TestThetaPathAvoidsExpensiveTerrain(t *testing.T) {
+	g := newTestGrid(
+		"..........",
+		".99999999.",
+		".99999999.",
+		"..........",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	start, end := Position{0, 1}, Position{9, 2}
+	path := pc.ThetaPath(start, end)
+	checkThetaPath(t, g, path, start, end)
+	for _, p := range PathCells(path) {
+		if g.Cost(p.X, p.Y) == 9 {
+			t.Fatalf("path crosses expensive terrain at %v: %v", p, path)
+		}
+	}
+}
+
+// on random grids, Theta* paths should be clear and never cost more than
+// the grid path A* finds (both costed by LineCost, allowing for rounding)
+func checkThetaAgainstAStar(t *testing.T, g *testGrid, seed int64) {
+	pc := NewPathComputer(g, g.w, g.h)
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < 30; i++ {
+		start, end := randomOpenCell(g, r), randomOpenCell(g, r)
+		grid := pc.Path(start, end)
+		path := pc.ThetaPath(start, end)
+		if (len(grid) == 0) != (len(path) == 0) {
+			t.Fatalf("seed %d: A* found %d cells, Theta* %d waypoints",
+				seed, len(grid), len(path))
+		}
+		if len(path) == 0 {
+			continue
+		}
+		checkThetaPath(t, g, path, start, end)
+		if theta, astar := lineCostOf(g, path), lineCostOf(g, grid); theta > astar+len(grid) {
+			t.Fatalf("seed %d: %v -> %v costs %d, A* %d",
+				seed, start, end, theta, astar)
+		}
+		// searching again (with N bumped) gives the same path
+		again := pc.ThetaPath(start, end)
+		if len(again) != len(path) {
+			t.Fatalf("seed %d: repeated search differs", seed)
+		}
+	}
+}
+
+func TestThetaPathRandom(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		checkThetaAgainstAStar(t, randomGrid(32, 32, 0.25, seed), seed)
+	}
+}
+
+func TestThetaPathWeighted(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		checkThetaAgainstAStar(t, randomWeightedGrid(32, 32, 0.1, seed), seed)
+	}
+}
+
+func BenchmarkThetaPath256Weighted(b *testing.B) {
+	g := randomWeightedGrid(256, 256, 0.1, 1)
+	pc := NewPathComputer(g, g.w, g.h)
+	r := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pc.ThetaPath(randomOpenCell(g, r), randomOpenCell(g, r))
+	}
+}
@@ -0,0 +1,216 @@
+package gridpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// what happened to a node on the open heap
+type TraceOp string
+
+const (
+	TRACE_PUSH   TraceOp = "push"
+	TRACE_POP    TraceOp = "pop"
+	TRACE_MODIFY TraceOp = "modify"
+)
+
+// one heap operation of a search, with the node's scores just after it
+type TraceStep struct {
+	Op TraceOp `json:"op"`
+	X  int     `json:"x"`
+	Y  int     `json:"y"`
+	G  int     `json:"g"`
+	H  int     `json:"h"`
+	F  int     `json:"f"`
+}
+
+func (s TraceStep) String() string {
+	return fmt.Sprintf("%s Node{[%d, %d], G: %d, H: %d, F: %d}",
+		s.Op, s.X, s.Y, s.G, s.H, s.F)
+}
+
+// Trace records every push, pop and modify on a PathComputer's open heap
+// while it's set as the PathComputer's Trace, so it covers Path, JPSPath,
+// ThetaPath and Dijkstra alike. Use a fresh Trace per search to look at
+// them one at a time
+//
+// Width:		width of the grid searched
+// Height:		height of the grid searched
+// Steps:		the heap operations, in the order they happened
+type Trace struct {
+	Width  int         `json:"width"`
+	Height int         `json:"height"`
+	Steps  []TraceStep `json:"steps"`
+}
+
+func NewTrace(w int, h int) *Trace {
+	return &Trace{Width: w, Height: h}
+}
+
+func (t *Trace) record(op TraceOp, pc *PathComputer, p Position) {
+	t.Steps = append(t.Steps, TraceStep{
+		Op: op,
+		X:  p.X,
+		Y:  p.Y,
+		G:  pc.G[p.X][p.Y],
+		H:  pc.H[p.X][p.Y],
+		F:  pc.F[p.X][p.Y],
+	})
+}
+
+// Reset drops the recorded steps, so the Trace can be reused
+func (t *Trace) Reset() {
+	t.Steps = t.Steps[:0]
+}
+
+// the number of steps of each kind
+func (t *Trace) Count(op TraceOp) int {
+	n := 0
+	for _, s := range t.Steps {
+		if s.Op == op {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *Trace) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return enc.Encode(t)
+}
+
+func ReadTrace(r io.Reader) (*Trace, error) {
+	var t Trace
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// the state of each cell at the end of the trace
+const (
+	TRACE_UNSEEN = iota
+	TRACE_OPEN
+	TRACE_CLOSED
+)
+
+// TraceGrid is the end state of a traced search, indexed [x][y] like the
+// PathComputer's arrays.
+//
+// List:		TRACE_UNSEEN, TRACE_OPEN or TRACE_CLOSED
+// G:			the last G recorded for the cell
+// Order:		when the cell was (last) popped, counting from 0; -1 if never
+// MaxG:		the largest G of any seen cell
+// Popped:		the number of pops
+type TraceGrid struct {
+	List   [][]int
+	G      [][]int
+	Order  [][]int
+	MaxG   int
+	Popped int
+}
+
+// replay the trace into the state of each cell at its end
+func (t *Trace) Grid() *TraceGrid {
+	tg := &TraceGrid{
+		List:  make([][]int, t.Width),
+		G:     make([][]int, t.Width),
+		Order: make([][]int, t.Width),
+	}
+	for x := 0; x < t.Width; x++ {
+		tg.List[x] = make([]int, t.Height)
+		tg.G[x] = make([]int, t.Height)
+		tg.Order[x] = make([]int, t.Height)
+		for y := range tg.Order[x] {
+			tg.Order[x][y] = -1
+		}
+	}
+	for _, s := range t.Steps {
+		if s.X < 0 || s.X >= t.Width || s.Y < 0 || s.Y >= t.Height {
+			continue
+		}
+		switch s.Op {
+		case TRACE_PUSH, TRACE_MODIFY:
+			tg.List[s.X][s.Y] = TRACE_OPEN
+		case TRACE_POP:
+			tg.List[s.X][s.Y] = TRACE_CLOSED
+			tg.Order[s.X][s.Y] = tg.Popped
+			tg.Popped++
+		}
+		tg.G[s.X][s.Y] = s.G
+		if s.G > tg.MaxG {
+			tg.MaxG = s.G
+		}
+	}
+	return tg
+}
+
+var (
+	TRACE_UNSEEN_COLOR = color.RGBA{0, 0, 0, 255}
+	TRACE_OPEN_COLOR   = color.RGBA{64, 192, 64, 255}
+	TRACE_CLOSED_COLOR = color.RGBA{64, 64, 192, 255}
+)
+
+// heat maps t in [0, 1] from dark blue through red to yellow
+func heat(t float64) color.RGBA {
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+	if t < 0.5 {
+		return color.RGBA{uint8(510 * t), 0, uint8(128 * (1 - 2*t)), 255}
+	}
+	return color.RGBA{255, uint8(510 * (t - 0.5)), 0, 255}
+}
+
+// Heatmap draws the trace as three panels side by side, each cell
+// cellPx pixels square with y increasing up the image: the open (green)
+// and closed (blue) sets, the G of every cell seen, and the order cells
+// were closed in (both as heat, cold to hot). Unseen cells are black
+func (t *Trace) Heatmap(cellPx int) *image.RGBA {
+	tg := t.Grid()
+	pw := t.Width * cellPx
+	img := image.NewRGBA(image.Rect(0, 0, 3*pw+2*cellPx, t.Height*cellPx))
+	fill := func(panel int, x int, y int, c color.RGBA) {
+		x0 := panel*(pw+cellPx) + x*cellPx
+		y0 := (t.Height - 1 - y) * cellPx
+		for py := y0; py < y0+cellPx; py++ {
+			for px := x0; px < x0+cellPx; px++ {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+	for x := 0; x < t.Width; x++ {
+		for y := 0; y < t.Height; y++ {
+			list := tg.List[x][y]
+			sets, g, order := TRACE_UNSEEN_COLOR, TRACE_UNSEEN_COLOR,
+				TRACE_UNSEEN_COLOR
+			if list == TRACE_OPEN {
+				sets = TRACE_OPEN_COLOR
+			}
+			if list == TRACE_CLOSED {
+				sets = TRACE_CLOSED_COLOR
+			}
+			if list != TRACE_UNSEEN && tg.MaxG > 0 {
+				g = heat(float64(tg.G[x][y]) / float64(tg.MaxG))
+			}
+			if list == TRACE_CLOSED && tg.Popped > 1 {
+				order = heat(float64(tg.Order[x][y]) / float64(tg.Popped-1))
+			}
+			fill(0, x, y, sets)
+			fill(1, x, y, g)
+			fill(2, x, y, order)
+		}
+	}
+	return img
+}
+
+func (t *Trace) WritePNG(w io.Writer, cellPx int) error {
+	return png.Encode(w, t.Heatmap(cellPx))
+}
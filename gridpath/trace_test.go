@@ -0,0 +1,122 @@
+package gridpath
+
+import (
+	"bytes"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func TestTraceCountsMatchSearch(t *testing.T) {
+	g := randomWeightedGrid(32, 32, 0.1, 1)
+	g.cells[0][0], g.cells[31][31] = '.', '.'
+	pc := NewPathComputer(g, g.w, g.h)
+	for _, alg := range []Algorithm{ASTAR, JPS, THETA} {
+		pc.Trace = NewTrace(g.w, g.h)
+		if _, err := pc.Find(Position{0, 0}, Position{31, 31}, alg); err != nil {
+			t.Fatal(err)
+		}
+		if pops := pc.Trace.Count(TRACE_POP); pops != pc.Expanded {
+			t.Fatalf("alg %d: traced %d pops, search expanded %d",
+				alg, pops, pc.Expanded)
+		}
+		first := pc.Trace.Steps[0]
+		if first.Op != TRACE_PUSH || first.X != 0 || first.Y != 0 ||
+			first.G != 0 {
+			t.Fatalf("alg %d: first step %v", alg, first)
+		}
+		last := pc.Trace.Steps[len(pc.Trace.Steps)-1]
+		if last.Op != TRACE_POP || last.X != 31 || last.Y != 31 ||
+			last.G != pc.G[31][31] {
+			t.Fatalf("alg %d: last step %v", alg, last)
+		}
+	}
+}
+
+func TestTraceModifyLowersG(t *testing.T) {
+	g := randomWeightedGrid(32, 32, 0.1, 2)
+	pc := NewPathComputer(g, g.w, g.h)
+	pc.Trace = NewTrace(g.w, g.h)
+	pc.Dijkstra(Position{0, 0})
+	lastG := make(map[Position]int)
+	modified := 0
+	for _, s := range pc.Trace.Steps {
+		p := Position{s.X, s.Y}
+		if s.Op == TRACE_MODIFY {
+			modified++
+			if s.G >= lastG[p] {
+				t.Fatalf("%v raised G from %d", s, lastG[p])
+			}
+		}
+		if s.F != s.G+s.H {
+			t.Fatalf("%v: F isn't G + H", s)
+		}
+		lastG[p] = s.G
+	}
+	if modified == 0 {
+		t.Fatal("no modify steps on a weighted grid")
+	}
+}
+
+func TestTraceJSONRoundTrip(t *testing.T) {
+	g := randomGrid(16, 16, 0.2, 3)
+	g.cells[0][0], g.cells[15][15] = '.', '.'
+	pc := NewPathComputer(g, g.w, g.h)
+	pc.Trace = NewTrace(g.w, g.h)
+	pc.Path(Position{0, 0}, Position{15, 15})
+	var buf bytes.Buffer
+	if err := pc.Trace.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, pc.Trace) {
+		t.Fatal("trace changed in the round trip")
+	}
+}
+
+func TestTraceHeatmap(t *testing.T) {
+	g := newTestGrid(
+		".....",
+		".....",
+		".....",
+	)
+	pc := NewPathComputer(g, g.w, g.h)
+	pc.Trace = NewTrace(g.w, g.h)
+	pc.Path(Position{0, 0}, Position{4, 0})
+	tg := pc.Trace.Grid()
+	if tg.List[0][0] != TRACE_CLOSED || tg.List[4][0] != TRACE_CLOSED {
+		t.Fatal("endpoints not closed")
+	}
+	if tg.Order[0][0] != 0 || tg.Order[4][0] != tg.Popped-1 {
+		t.Fatalf("start closed %dth, end %dth of %d",
+			tg.Order[0][0], tg.Order[4][0], tg.Popped)
+	}
+	if tg.List[4][2] != TRACE_UNSEEN {
+		t.Fatal("far corner was seen")
+	}
+
+	var buf bytes.Buffer
+	if err := pc.Trace.WritePNG(&buf, 4); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 3*5*4+2*4 || b.Dy() != 3*4 {
+		t.Fatalf("heatmap is %v", b)
+	}
+	// cell (0, 0) is at the bottom left of the first panel
+	r, gr, b, _ := img.At(1, 3*4-1).RGBA()
+	cr, cg, cb, _ := TRACE_CLOSED_COLOR.RGBA()
+	if r != cr || gr != cg || b != cb {
+		t.Fatal("start cell isn't drawn closed")
+	}
+	r, gr, b, _ = img.At(5*4-1, 0).RGBA()
+	if r != 0 || gr != 0 || b != 0 {
+		t.Fatal("far corner isn't drawn unseen")
+	}
+}
@@ -0,0 +1,92 @@
+// Package heightfield post-processes heightfields, such as the [][]float64
+// fields PerlinNoiseInt2D returns (indexed [y][x]), with hydraulic and
+// thermal erosion
+package heightfield
+
+// a new w x h field of zeroes
+func New(w int, h int) [][]float64 {
+	f := make([][]float64, h)
+	for y := range f {
+		f[y] = make([]float64, w)
+	}
+	return f
+}
+
+// whether f has no cells at all
+func empty(f [][]float64) bool {
+	return len(f) == 0 || len(f[0]) == 0
+}
+
+// a copy of f
+func Copy(f [][]float64) [][]float64 {
+	c := make([][]float64, len(f))
+	for y := range f {
+		c[y] = append([]float64(nil), f[y]...)
+	}
+	return c
+}
+
+// the sum of every cell of f
+func Sum(f [][]float64) float64 {
+	s := 0.0
+	for _, row := range f {
+		for _, v := range row {
+			s += v
+		}
+	}
+	return s
+}
+
+// the largest cell of f (0 if f is empty or all below 0)
+func Max(f [][]float64) float64 {
+	m := 0.0
+	for _, row := range f {
+		for _, v := range row {
+			if v > m {
+				m = v
+			}
+		}
+	}
+	return m
+}
+
+// add b into a, cell by cell (they must be the same size)
+func AddInto(a [][]float64, b [][]float64) {
+	for y := range a {
+		for x := range a[y] {
+			a[y][x] += b[y][x]
+		}
+	}
+}
+
+// scale f in place so its largest cell is 1 (unless it's all 0)
+func Normalize(f [][]float64) {
+	m := Max(f)
+	if m == 0 {
+		return
+	}
+	for _, row := range f {
+		for x := range row {
+			row[x] /= m
+		}
+	}
+}
+
+// the parameters of both passes Erode runs
+type ErosionParams struct {
+	Hydraulic HydraulicParams
+	Thermal   ThermalParams
+}
+
+var DEFAULT_EROSION = ErosionParams{
+	Hydraulic: DEFAULT_HYDRAULIC,
+	Thermal:   DEFAULT_THERMAL,
+}
+
+// Erode runs hydraulic then thermal erosion over f in place, returning the
+// sediment each cell received from both
+func Erode(f [][]float64, p ErosionParams) [][]float64 {
+	sediment := Hydraulic(f, p.Hydraulic)
+	AddInto(sediment, Thermal(f, p.Thermal))
+	return sediment
+}
@@ -0,0 +1,131 @@
+package heightfield
+
+import (
+	"math"
+	"math/rand"
+)
+
+// the parameters of droplet erosion: each droplet runs downhill from a
+// random point, picking up sediment while it's fast and the slope is steep
+// and dropping it where it slows or climbs, until it evaporates or leaves
+// the field.
+//
+// Droplets:	how many droplets to run
+// Seed:		where the droplets start is drawn from this
+// MaxSteps:	the most cells a droplet runs over
+// Inertia:		how much a droplet keeps its direction rather than following the slope (0-1)
+// Capacity:	sediment a droplet can carry per unit of speed, water and slope
+// MinSlope:	the slope capacity is reckoned with on flatter ground
+// Erosion:		fraction of its spare capacity a droplet picks up each step
+// Deposition:	fraction of its excess sediment a droplet drops each step
+// Evaporation:	fraction of its water a droplet loses each step
+// Gravity:		how fast droplets speed up going downhill
+type HydraulicParams struct {
+	Droplets    int
+	Seed        int64
+	MaxSteps    int
+	Inertia     float64
+	Capacity    float64
+	MinSlope    float64
+	Erosion     float64
+	Deposition  float64
+	Evaporation float64
+	Gravity     float64
+}
+
+var DEFAULT_HYDRAULIC = HydraulicParams{
+	Droplets:    10000,
+	MaxSteps:    64,
+	Inertia:     0.05,
+	Capacity:    4,
+	MinSlope:    0.01,
+	Erosion:     0.3,
+	Deposition:  0.3,
+	Evaporation: 0.02,
+	Gravity:     4,
+}
+
+// the height of f at (x, y) by bilinear interpolation, and its gradient
+// there. x and y must be within [0, w-1) and [0, h-1)
+func heightAndGradient(
+	f [][]float64, x float64, y float64) (float64, float64, float64) {
+	cx, cy := int(x), int(y)
+	u, v := x-float64(cx), y-float64(cy)
+	h00 := f[cy][cx]
+	h10 := f[cy][cx+1]
+	h01 := f[cy+1][cx]
+	h11 := f[cy+1][cx+1]
+	gx := (h10-h00)*(1-v) + (h11-h01)*v
+	gy := (h01-h00)*(1-u) + (h11-h10)*u
+	h := h00*(1-u)*(1-v) + h10*u*(1-v) + h01*(1-u)*v + h11*u*v
+	return h, gx, gy
+}
+
+// Hydraulic runs p.Droplets droplets over f, eroding it in place, and
+// returns the sediment each cell received. Sediment a droplet still carries
+// when it leaves the field is lost
+func Hydraulic(f [][]float64, p HydraulicParams) [][]float64 {
+	if empty(f) {
+		return New(0, len(f))
+	}
+	sediment := New(len(f[0]), len(f))
+	if len(f) < 2 || len(f[0]) < 2 {
+		return sediment
+	}
+	w, h := float64(len(f[0])-1), float64(len(f)-1)
+	rng := rand.New(rand.NewSource(p.Seed))
+	// change the four cells around (x, y) by amount, split bilinearly
+	spread := func(x float64, y float64, amount float64, out [][]float64) {
+		cx, cy := int(x), int(y)
+		u, v := x-float64(cx), y-float64(cy)
+		ws := [4]float64{(1 - u) * (1 - v), u * (1 - v), (1 - u) * v, u * v}
+		cells := [4][2]int{{cx, cy}, {cx + 1, cy}, {cx, cy + 1}, {cx + 1, cy + 1}}
+		for i, c := range cells {
+			f[c[1]][c[0]] += amount * ws[i]
+			if out != nil {
+				out[c[1]][c[0]] += amount * ws[i]
+			}
+		}
+	}
+	for i := 0; i < p.Droplets; i++ {
+		x, y := rng.Float64()*w, rng.Float64()*h
+		dx, dy := 0.0, 0.0
+		speed, water, carried := 1.0, 1.0, 0.0
+		for step := 0; step < p.MaxSteps; step++ {
+			height, gx, gy := heightAndGradient(f, x, y)
+			dx = dx*p.Inertia - gx*(1-p.Inertia)
+			dy = dy*p.Inertia - gy*(1-p.Inertia)
+			l := math.Sqrt(dx*dx + dy*dy)
+			if l == 0 {
+				break
+			}
+			dx, dy = dx/l, dy/l
+			nx, ny := x+dx, y+dy
+			if nx < 0 || nx >= w || ny < 0 || ny >= h {
+				break
+			}
+			newHeight, _, _ := heightAndGradient(f, nx, ny)
+			dh := newHeight - height
+			capacity := math.Max(-dh, p.MinSlope) * speed * water * p.Capacity
+			if carried > capacity || dh > 0 {
+				// uphill, fill the pit behind (no higher than the new
+				// point); otherwise drop some of the excess
+				drop := (carried - capacity) * p.Deposition
+				if dh > 0 {
+					drop = math.Min(dh, carried)
+				}
+				carried -= drop
+				spread(x, y, drop, sediment)
+			} else {
+				// never dig deeper than the drop to the next point
+				take := math.Min((capacity-carried)*p.Erosion, -dh)
+				carried += take
+				spread(x, y, -take, nil)
+			}
+			speed = math.Sqrt(math.Max(0, speed*speed-dh*p.Gravity))
+			water *= 1 - p.Evaporation
+			x, y = nx, ny
+		}
+	}
+	return sediment
+}
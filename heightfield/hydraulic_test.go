@@ -0,0 +1,95 @@
+package heightfield
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// a w x h cone peaking at 1 in the middle
+func cone(w int, h int) [][]float64 {
+	f := New(w, h)
+	cx, cy := float64(w-1)/2, float64(h-1)/2
+	for y := range f {
+		for x := range f[y] {
+			d := math.Hypot(float64(x)-cx, float64(y)-cy) / cx
+			f[y][x] = math.Max(0, 1-d)
+		}
+	}
+	return f
+}
+
+func TestHydraulicFlatStaysFlat(t *testing.T) {
+	f := New(32, 32)
+	for y := range f {
+		for x := range f[y] {
+			f[y][x] = 0.5
+		}
+	}
+	p := DEFAULT_HYDRAULIC
+	p.Droplets = 1000
+	sediment := Hydraulic(f, p)
+	for y := range f {
+		for x := range f[y] {
+			if f[y][x] != 0.5 || sediment[y][x] != 0 {
+				t.Fatalf("flat field changed at %d, %d", x, y)
+			}
+		}
+	}
+}
+
+func TestHydraulicErodesCone(t *testing.T) {
+	f := cone(64, 64)
+	before := Copy(f)
+	p := DEFAULT_HYDRAULIC
+	p.Seed = 1
+	sediment := Hydraulic(f, p)
+	// material only moves or leaves the field
+	if Sum(f) > Sum(before)+1e-9 {
+		t.Fatalf("total height rose from %g to %g", Sum(before), Sum(f))
+	}
+	// the slopes are cut into, and the sediment settles lower down than
+	// where it was taken from
+	eroded, cut, settled := 0.0, 0.0, 0.0
+	for y := range f {
+		for x := range f[y] {
+			if d := before[y][x] - f[y][x]; d > 0 {
+				eroded += d
+				cut += d * before[y][x]
+			}
+			settled += sediment[y][x] * before[y][x]
+		}
+	}
+	if eroded == 0 || Sum(sediment) == 0 {
+		t.Fatal("nothing eroded")
+	}
+	if settled/Sum(sediment) >= cut/eroded {
+		t.Fatalf("sediment settles at height %.3f, taken from %.3f",
+			settled/Sum(sediment), cut/eroded)
+	}
+}
+
+func TestHydraulicIsReproducible(t *testing.T) {
+	p := DEFAULT_HYDRAULIC
+	p.Droplets = 2000
+	p.Seed = 7
+	a, b := cone(32, 32), cone(32, 32)
+	sa, sb := Hydraulic(a, p), Hydraulic(b, p)
+	if !reflect.DeepEqual(a, b) || !reflect.DeepEqual(sa, sb) {
+		t.Fatal("same seed eroded differently")
+	}
+	p.Seed = 8
+	c := cone(32, 32)
+	Hydraulic(c, p)
+	if reflect.DeepEqual(a, c) {
+		t.Fatal("different seeds eroded the same")
+	}
+}
+
+func TestHydraulicEmpty(t *testing.T) {
+	for _, f := range [][][]float64{nil, {}, {{}}, {{}, {}}} {
+		if sediment := Hydraulic(f, DEFAULT_HYDRAULIC); len(sediment) != len(f) {
+			t.Fatalf("%d rows of sediment for %d rows", len(sediment), len(f))
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package heightfield
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// a cell of a field, x along a row and y down the rows (so f[Y][X])
+type Point struct {
+	X int
+	Y int
+}
+
+// where a cell's water goes when it runs off the edge of the field
+var OFF_MAP = Point{-1, -1}
+
+// how far each filled cell is raised above the one it drains into, so
+// that flats and filled pits still slope toward their outlet
+const FILL_EPSILON = 1e-9
+
+// a cell waiting to be flooded, lowest first
+type floodCell struct {
+	p Point
+	h float64
+}
+
+type floodQueue []floodCell
+
+func (q floodQueue) Len() int            { return len(q) }
+func (q floodQueue) Less(i, j int) bool  { return q[i].h < q[j].h }
+func (q floodQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *floodQueue) Push(x interface{}) { *q = append(*q, x.(floodCell)) }
+func (q *floodQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// Drainage gives the cell each cell of f drains into: its lowest neighbour
+// once every pit has been filled up to its spill point (priority-flood),
+// so that all water reaches the edge of the field, where it runs OFF_MAP.
+// Also returns the filled heights. f itself is unchanged
+func Drainage(f [][]float64) (down [][]Point, filled [][]float64) {
+	if empty(f) {
+		return make([][]Point, len(f)), Copy(f)
+	}
+	hgt, wid := len(f), len(f[0])
+	filled = Copy(f)
+	down = make([][]Point, hgt)
+	done := make([][]bool, hgt)
+	for y := range down {
+		down[y] = make([]Point, wid)
+		done[y] = make([]bool, wid)
+	}
+	var q floodQueue
+	// the edge drains off the map, and the flood rises inward from it
+	for y := 0; y < hgt; y++ {
+		for x := 0; x < wid; x++ {
+			if x == 0 || y == 0 || x == wid-1 || y == hgt-1 {
+				down[y][x] = OFF_MAP
+				done[y][x] = true
+				heap.Push(&q, floodCell{Point{x, y}, filled[y][x]})
+			}
+		}
+	}
+	for q.Len() > 0 {
+		c := heap.Pop(&q).(floodCell)
+		for _, d := range neighbours {
+			nx, ny := c.p.X+d[0], c.p.Y+d[1]
+			if nx < 0 || nx >= wid || ny < 0 || ny >= hgt || done[ny][nx] {
+				continue
+			}
+			done[ny][nx] = true
+			// reached from c, the lowest way out, so it drains into c
+			if filled[ny][nx] <= c.h {
+				filled[ny][nx] = c.h + FILL_EPSILON
+			}
+			down[ny][nx] = c.p
+			heap.Push(&q, floodCell{Point{nx, ny}, filled[ny][nx]})
+		}
+	}
+	return down, filled
+}
+
+// FlowAccumulation gives the number of cells (counting itself) whose water
+// runs through each cell, following down from Drainage
+func FlowAccumulation(down [][]Point, filled [][]float64) [][]float64 {
+	if empty(filled) {
+		return New(0, len(filled))
+	}
+	hgt, wid := len(filled), len(filled[0])
+	acc := New(wid, hgt)
+	order := make([]Point, 0, wid*hgt)
+	for y := 0; y < hgt; y++ {
+		for x := 0; x < wid; x++ {
+			acc[y][x] = 1
+			order = append(order, Point{x, y})
+		}
+	}
+	// highest first, so each cell's flow is complete before it's passed on
+	sortByHeight(order, filled)
+	for _, p := range order {
+		if d := down[p.Y][p.X]; d != OFF_MAP {
+			acc[d.Y][d.X] += acc[p.Y][p.X]
+		}
+	}
+	return acc
+}
+
+func sortByHeight(ps []Point, f [][]float64) {
+	q := make(floodQueue, len(ps))
+	for i, p := range ps {
+		q[i] = floodCell{p, -f[p.Y][p.X]}
+	}
+	heap.Init(&q)
+	for i := range ps {
+		ps[i] = heap.Pop(&q).(floodCell).p
+	}
+}
+
+// how a river ends
+type RiverEnd int
+
+const (
+	// it runs into a lake
+	RIVER_LAKE RiverEnd = iota
+	// it runs off the edge of the field
+	RIVER_EDGE
+	// it joins a river traced before it, on its last point
+	RIVER_JOIN
+)
+
+// a river, traced cell by cell from its source.
+//
+// Points:		the cells the river runs through, source first
+// Flow:		the flow accumulation at each point
+// Widths:		the river's width (in cells) at each point
+// End:			how the river ends
+type River struct {
+	Points []Point
+	Flow   []float64
+	Widths []float64
+	End    RiverEnd
+}
+
+// the parameters of river tracing.
+//
+// MinFlow:		cells draining at least this many cells carry a river
+// Width:		the width (in cells) of a river carrying MinFlow
+// MaxWidth:	no river is wider than this
+type RiverParams struct {
+	MinFlow  float64
+	Width    float64
+	MaxWidth float64
+}
+
+var DEFAULT_RIVERS = RiverParams{
+	MinFlow:  64,
+	Width:    0.5,
+	MaxWidth: 4,
+}
+
+// the width of a river carrying flow: it grows with the square root of the
+// flow (so with the river's cross-section, for a fixed depth/width ratio)
+func (p RiverParams) WidthOf(flow float64) float64 {
+	return math.Min(p.MaxWidth, p.Width*math.Sqrt(flow/p.MinFlow))
+}
+
+// Rivers traces every river over the drainage: from each source (a river
+// cell nothing upstream of which carries a river) down until it runs into
+// a cell for which inLake is true (nil for no lakes), off the edge, or
+// into a river already traced. Rivers are traced in order of the flow
+// accumulation where they end (the longest first among those ending in the
+// same place), so a tributary ends where it joins the main stream
+func Rivers(down [][]Point, acc [][]float64, p RiverParams,
+	inLake func(x int, y int) bool) []River {
+	if empty(acc) {
+		return nil
+	}
+	hgt, wid := len(acc), len(acc[0])
+	isRiver := func(q Point) bool {
+		return acc[q.Y][q.X] >= p.MinFlow &&
+			(inLake == nil || !inLake(q.X, q.Y))
+	}
+	// a river cell fed by another river cell isn't a source
+	fed := make([][]bool, hgt)
+	for y := range fed {
+		fed[y] = make([]bool, wid)
+	}
+	for y := 0; y < hgt; y++ {
+		for x := 0; x < wid; x++ {
+			d := down[y][x]
+			if d != OFF_MAP && isRiver(Point{x, y}) {
+				fed[d.Y][d.X] = true
+			}
+		}
+	}
+	var sources []Point
+	for y := 0; y < hgt; y++ {
+		for x := 0; x < wid; x++ {
+			if isRiver(Point{x, y}) && !fed[y][x] {
+				sources = append(sources, Point{x, y})
+			}
+		}
+	}
+	// the flow at the last river cell below each source, and how many
+	// river cells it takes to get there
+	type mouth struct {
+		flow   float64
+		length int
+	}
+	mouths := make(map[Point]mouth, len(sources))
+	for _, s := range sources {
+		var m mouth
+		for q := s; q != OFF_MAP && isRiver(q); q = down[q.Y][q.X] {
+			m.flow = acc[q.Y][q.X]
+			m.length++
+		}
+		mouths[s] = m
+	}
+	sort.Slice(sources, func(i int, j int) bool {
+		a, b := mouths[sources[i]], mouths[sources[j]]
+		if a.flow != b.flow {
+			return a.flow > b.flow
+		}
+		if a.length != b.length {
+			return a.length > b.length
+		}
+		return sources[i].Y < sources[j].Y ||
+			(sources[i].Y == sources[j].Y && sources[i].X < sources[j].X)
+	})
+
+	traced := make([][]bool, hgt)
+	for y := range traced {
+		traced[y] = make([]bool, wid)
+	}
+	rivers := make([]River, 0, len(sources))
+	for _, s := range sources {
+		var r River
+		add := func(q Point) {
+			r.Points = append(r.Points, q)
+			r.Flow = append(r.Flow, acc[q.Y][q.X])
+			r.Widths = append(r.Widths, p.WidthOf(acc[q.Y][q.X]))
+		}
+		q := s
+		for {
+			add(q)
+			if traced[q.Y][q.X] {
+				r.End = RIVER_JOIN
+				break
+			}
+			traced[q.Y][q.X] = true
+			next := down[q.Y][q.X]
+			if next == OFF_MAP {
+				r.End = RIVER_EDGE
+				break
+			}
+			if inLake != nil && inLake(next.X, next.Y) {
+				add(next)
+				r.End = RIVER_LAKE
+				break
+			}
+			q = next
+		}
+		rivers = append(rivers, r)
+	}
+	return rivers
+}
+
+// RiverWidths rasterizes rivers onto a w x h field: each cell gets the
+// width of the widest river whose course passes within half its width of
+// the cell (0 for dry cells)
+func RiverWidths(rivers []River, w int, h int) [][]float64 {
+	out := New(w, h)
+	for _, r := range rivers {
+		for i, q := range r.Points {
+			width := r.Widths[i]
+			reach := int(math.Ceil(width / 2))
+			for dy := -reach; dy <= reach; dy++ {
+				for dx := -reach; dx <= reach; dx++ {
+					x, y := q.X+dx, q.Y+dy
+					if x < 0 || x >= w || y < 0 || y >= h {
+						continue
+					}
+					if (dx != 0 || dy != 0) &&
+						math.Hypot(float64(dx), float64(dy)) > width/2 {
+						continue
+					}
+					if width > out[y][x] {
+						out[y][x] = width
+					}
+				}
+			}
+		}
+	}
+	return out
+}
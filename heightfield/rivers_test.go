@@ -0,0 +1,174 @@
+package heightfield
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// a w x h valley running down the middle column, falling toward y = 0
+func valley(w int, h int) [][]float64 {
+	f := New(w, h)
+	cx := float64(w-1) / 2
+	for y := range f {
+		for x := range f[y] {
+			f[y][x] = math.Abs(float64(x)-cx) + 0.1*float64(y)
+		}
+	}
+	return f
+}
+
+// a w x h field of seeded noise, full of pits and flats
+func noise(w int, h int, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+	f := New(w, h)
+	for y := range f {
+		for x := range f[y] {
+			f[y][x] = math.Floor(r.Float64()*8) / 8
+		}
+	}
+	return f
+}
+
+// every cell's water should reach the edge, and everything reaching the
+// edge should account for every cell of the field
+func TestDrainageReachesEdge(t *testing.T) {
+	for seed := int64(0); seed < 4; seed++ {
+		f := noise(24, 16, seed)
+		down, filled := Drainage(f)
+		for y := range down {
+			for x := range down[y] {
+				q, steps := Point{x, y}, 0
+				for down[q.Y][q.X] != OFF_MAP {
+					next := down[q.Y][q.X]
+					if filled[next.Y][next.X] >= filled[q.Y][q.X] {
+						t.Fatalf("seed %d: %v drains uphill to %v",
+							seed, q, next)
+					}
+					q = next
+					if steps++; steps > 24*16 {
+						t.Fatalf("seed %d: %d, %d never drains", seed, x, y)
+					}
+				}
+			}
+		}
+		acc := FlowAccumulation(down, filled)
+		out := 0.0
+		for y := range down {
+			for x := range down[y] {
+				if down[y][x] == OFF_MAP {
+					out += acc[y][x]
+				}
+			}
+		}
+		if out != 24*16 {
+			t.Fatalf("seed %d: %.0f cells drain off the map, want %d",
+				seed, out, 24*16)
+		}
+	}
+}
+
+func TestRiversToEdge(t *testing.T) {
+	f := valley(33, 32)
+	down, filled := Drainage(f)
+	acc := FlowAccumulation(down, filled)
+	p := RiverParams{MinFlow: 8, Width: 0.5, MaxWidth: 3}
+	rivers := Rivers(down, acc, p, nil)
+	if len(rivers) == 0 {
+		t.Fatal("no rivers down the valley")
+	}
+	main := rivers[0]
+	if main.End != RIVER_EDGE {
+		t.Fatalf("main river ends %d, want RIVER_EDGE", main.End)
+	}
+	if mouth := main.Points[len(main.Points)-1]; mouth.Y != 0 {
+		t.Fatalf("main river leaves the map at %v, want y = 0", mouth)
+	}
+	for i := 1; i < len(main.Points); i++ {
+		if main.Widths[i] < main.Widths[i-1] {
+			t.Fatalf("river narrows from %.2f to %.2f at %v",
+				main.Widths[i-1], main.Widths[i], main.Points[i])
+		}
+		if main.Widths[i] > p.MaxWidth {
+			t.Fatalf("river is %.2f wide, max %.2f", main.Widths[i], p.MaxWidth)
+		}
+	}
+	widths := RiverWidths(rivers, 33, 32)
+	for _, q := range main.Points {
+		if widths[q.Y][q.X] == 0 {
+			t.Fatalf("river cell %v has no width", q)
+		}
+	}
+	if widths[16][0] != 0 {
+		t.Fatal("valley side is wet")
+	}
+}
+
+func TestRiversIntoLake(t *testing.T) {
+	f := valley(33, 32)
+	down, filled := Drainage(f)
+	acc := FlowAccumulation(down, filled)
+	inLake := func(x int, y int) bool {
+		return y < 6 && x > 12 && x < 20
+	}
+	rivers := Rivers(down, acc, RiverParams{8, 0.5, 3}, inLake)
+	if len(rivers) == 0 {
+		t.Fatal("no rivers down the valley")
+	}
+	for _, r := range rivers {
+		for _, q := range r.Points[:len(r.Points)-1] {
+			if inLake(q.X, q.Y) {
+				t.Fatalf("river runs on through the lake at %v", q)
+			}
+		}
+	}
+	main := rivers[0]
+	mouth := main.Points[len(main.Points)-1]
+	if main.End != RIVER_LAKE || !inLake(mouth.X, mouth.Y) {
+		t.Fatalf("main river ends %d at %v, want RIVER_LAKE", main.End, mouth)
+	}
+}
+
+// tributaries end on a cell of a river traced before them, and no cell is
+// the course of two rivers otherwise
+func TestRiversJoin(t *testing.T) {
+	f := noise(48, 48, 7)
+	down, filled := Drainage(f)
+	acc := FlowAccumulation(down, filled)
+	rivers := Rivers(down, acc, RiverParams{16, 0.5, 3}, nil)
+	seen := make(map[Point]bool)
+	joins := 0
+	for i, r := range rivers {
+		last := len(r.Points) - 1
+		for j, q := range r.Points {
+			if j == last && r.End == RIVER_JOIN {
+				if !seen[q] {
+					t.Fatalf("river %d joins nothing at %v", i, q)
+				}
+				joins++
+				continue
+			}
+			if seen[q] {
+				t.Fatalf("river %d runs over another at %v", i, q)
+			}
+			seen[q] = true
+		}
+	}
+	if joins == 0 {
+		t.Fatal("no tributaries")
+	}
+}
+
+func TestRiversEmpty(t *testing.T) {
+	for _, f := range [][][]float64{nil, {}, {{}}} {
+		down, filled := Drainage(f)
+		acc := FlowAccumulation(down, filled)
+		if len(down) != len(f) || len(acc) != len(f) {
+			t.Fatalf("%d rows of drainage, %d of flow for %d rows",
+				len(down), len(acc), len(f))
+		}
+		if rivers := Rivers(down, acc, DEFAULT_RIVERS, nil); len(rivers) != 0 {
+			t.Fatalf("%d rivers on an empty field", len(rivers))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package heightfield
+
+import (
+	"math"
+	"math/rand"
+)
+
+// the parameters of thermal (talus) erosion: wherever a cell stands more
+// than Talus above a neighbour, material slumps from it onto the lower
+// neighbours.
+//
+// Iterations:	how many passes over the field to make
+// Seed:		the order cells are visited in each pass is shuffled from this
+// Talus:		the steepest stable height difference between side-by-side cells
+// Rate:		fraction of the excess over Talus moved in each slump (0-1)
+type ThermalParams struct {
+	Iterations int
+	Seed       int64
+	Talus      float64
+	Rate       float64
+}
+
+var DEFAULT_THERMAL = ThermalParams{
+	Iterations: 32,
+	Talus:      0.02,
+	Rate:       0.5,
+}
+
+var neighbours = [8][2]int{
+	{-1, -1}, {0, -1}, {1, -1},
+	{-1, 0}, {1, 0},
+	{-1, 1}, {0, 1}, {1, 1},
+}
+
+// Thermal runs p.Iterations passes of thermal erosion over f in place,
+// returning the material each cell received. The total height of f is
+// unchanged
+func Thermal(f [][]float64, p ThermalParams) [][]float64 {
+	if empty(f) {
+		return New(0, len(f))
+	}
+	sediment := New(len(f[0]), len(f))
+	hgt, wid := len(f), len(f[0])
+	rng := rand.New(rand.NewSource(p.Seed))
+	order := make([]int, wid*hgt)
+	for i := range order {
+		order[i] = i
+	}
+	var excess [8]float64
+	for it := 0; it < p.Iterations; it++ {
+		rng.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+		for _, i := range order {
+			x, y := i%wid, i/wid
+			// how far each neighbour is below the angle of repose
+			most, total := 0.0, 0.0
+			for n, d := range neighbours {
+				excess[n] = 0
+				nx, ny := x+d[0], y+d[1]
+				if nx < 0 || nx >= wid || ny < 0 || ny >= hgt {
+					continue
+				}
+				talus := p.Talus
+				if d[0] != 0 && d[1] != 0 {
+					talus *= math.Sqrt2
+				}
+				if e := f[y][x] - f[ny][nx] - talus; e > 0 {
+					excess[n] = e
+					total += e
+					if e > most {
+						most = e
+					}
+				}
+			}
+			if total == 0 {
+				continue
+			}
+			// move enough to close part of the steepest drop, shared out
+			// in proportion to each drop
+			moved := p.Rate * most / 2
+			f[y][x] -= moved
+			for n, d := range neighbours {
+				if excess[n] > 0 {
+					share := moved * excess[n] / total
+					f[y+d[1]][x+d[0]] += share
+					sediment[y+d[1]][x+d[0]] += share
+				}
+			}
+		}
+	}
+	return sediment
+}
@@ -0,0 +1,78 @@
+package heightfield
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// the steepest drop between side-by-side (not diagonal) cells
+func steepest(f [][]float64) float64 {
+	most := 0.0
+	for y := range f {
+		for x := range f[y] {
+			if x+1 < len(f[y]) {
+				most = math.Max(most, math.Abs(f[y][x]-f[y][x+1]))
+			}
+			if y+1 < len(f) {
+				most = math.Max(most, math.Abs(f[y][x]-f[y+1][x]))
+			}
+		}
+	}
+	return most
+}
+
+func TestThermalSlumpsSpike(t *testing.T) {
+	f := New(16, 16)
+	f[8][8] = 1
+	p := DEFAULT_THERMAL
+	p.Iterations = 500
+	sediment := Thermal(f, p)
+	if math.Abs(Sum(f)-1) > 1e-9 {
+		t.Fatalf("total height changed to %g", Sum(f))
+	}
+	if s := steepest(f); s > p.Talus*1.5 {
+		t.Fatalf("steepest drop %g after slumping, talus %g", s, p.Talus)
+	}
+	if sediment[8][9] == 0 || sediment[8][8] != 0 {
+		t.Fatal("material didn't land around the spike")
+	}
+}
+
+func TestThermalLeavesGentleSlopes(t *testing.T) {
+	f := New(16, 16)
+	for y := range f {
+		for x := range f[y] {
+			f[y][x] = float64(x) * DEFAULT_THERMAL.Talus / 2
+		}
+	}
+	before := Copy(f)
+	Thermal(f, DEFAULT_THERMAL)
+	if !reflect.DeepEqual(f, before) {
+		t.Fatal("a slope under the talus angle slumped")
+	}
+}
+
+func TestErodeIsReproducible(t *testing.T) {
+	p := DEFAULT_EROSION
+	p.Hydraulic.Droplets = 2000
+	a, b := cone(32, 32), cone(32, 32)
+	sa, sb := Erode(a, p), Erode(b, p)
+	if !reflect.DeepEqual(a, b) || !reflect.DeepEqual(sa, sb) {
+		t.Fatal("same parameters eroded differently")
+	}
+	p.Thermal.Seed = 1
+	c := cone(32, 32)
+	Erode(c, p)
+	if reflect.DeepEqual(a, c) {
+		t.Fatal("thermal seed made no difference")
+	}
+}
+
+func TestThermalEmpty(t *testing.T) {
+	for _, f := range [][][]float64{nil, {}, {{}}, {{}, {}}} {
+		if sediment := Thermal(f, DEFAULT_THERMAL); len(sediment) != len(f) {
+			t.Fatalf("%d rows of sediment for %d rows", len(sediment), len(f))
+		}
+	}
+}
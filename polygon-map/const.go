@@ -1,5 +1,9 @@
 package main
 
+import (
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
+)
+
 const WORLD_HEIGHT = 1024
 const WORLD_WIDTH = 1024
 
@@ -16,3 +20,19 @@ const WATER_CUTOFF = 0.6
 
 const DRAW_LAKE_SOURCE = true
 const DRAW_LAKE_VERTICES = true
+
+// droplets of hydraulic erosion run over the perlin per cell (0 for none)
+const EROSION_DROPLETS_PER_CELL = 0.0
+
+// rivers run wherever at least MinFlow perlin cells drain through, and are
+// Width perlin cells wide there, widening with flow up to MaxWidth
+var RIVER_PARAMS = heightfield.RiverParams{
+	MinFlow:  48,
+	Width:    0.5,
+	MaxWidth: 3,
+}
+
+// extra cost, per perlin cell of width, of stepping into a river
+const RIVER_CROSSING_COST = 4.0
+
+const DRAW_RIVERS = true
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
 	"github.com/veandco/go-sdl2/gfx"
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -39,6 +40,23 @@ func drawLakePoints(r *sdl.Renderer, l *Lake) {
 	}
 }
 
+func drawRiver(r *sdl.Renderer, river heightfield.River) {
+	c := sdl.Color{0, 64, 200, 255}
+	// a perlin cell's width in screen pixels
+	cellPx := PSCALE * float64(WINDOW_WIDTH) / float64(WORLD_WIDTH)
+	for i := 1; i < len(river.Points); i++ {
+		p0, p1 := river.Points[i-1], river.Points[i]
+		s0 := worldSpaceToScreenSpace(perlinCellCenter(p0.X, p0.Y))
+		s1 := worldSpaceToScreenSpace(perlinCellCenter(p1.X, p1.Y))
+		width := int32(river.Widths[i]*cellPx + 0.5)
+		if width < 1 {
+			width = 1
+		}
+		gfx.ThickLineColor(r,
+			int32(s0.X), int32(s0.Y), int32(s1.X), int32(s1.Y), width, c)
+	}
+}
+
 func (w *World) DrawWorldMap(r *sdl.Renderer) {
 	r.SetDrawColor(0, 0, 0, 255)
 	r.FillRect(nil)
@@ -50,6 +68,11 @@ func (w *World) DrawWorldMap(r *sdl.Renderer) {
 			drawLake(r, l)
 		}
 	}
+	if DRAW_RIVERS {
+		for _, river := range w.m.Rivers {
+			drawRiver(r, river)
+		}
+	}
 	for _, l := range w.m.Lakes {
 		drawLakePoints(r, l)
 	}
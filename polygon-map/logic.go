@@ -1,52 +1,66 @@
 package main
 
 import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 	"math"
 	"time"
 )
 
-func (w *World) ComputePath() float64 {
+func (w *World) ComputePath() (float64, error) {
 	return w.ComputeEntityPathUnrolled()
 }
 
-func (w *World) ComputeEntityPathUnrolled() float64 {
+// ComputeEntityPathUnrolled paths the entity to its move target, returning
+// how long it took and, if the target can't be reached, why
+func (w *World) ComputeEntityPathUnrolled() (float64, error) {
 	var t_ms float64
+	var err error
 	if w.e != nil && w.e.moveTarget != nil {
 		t0 := time.Now()
-		path, _, found := w.c.Path(&w.e.pos, w.e.moveTarget)
+		var path []Point2D
+		path, err = w.terrainPath(w.e.pos, *w.e.moveTarget)
 		t_ms = float64(time.Since(t0).Nanoseconds()) / float64(1e6)
-		if found {
+		if err == nil {
 			w.e.path = path
 		}
 	}
-	w.c.Clear()
-	return t_ms
+	return t_ms, err
 }
 
+// terrainPath finds a path over the TerrainGrid, around lakes and across
+// rivers where they're cheapest to cross. Like the entity's path, it runs
+// from the goal back to the first step after from
+func (w *World) terrainPath(from Point2D, to Point2D) ([]Point2D, error) {
+	fx, fy := perlinCellAt(from)
+	tx, ty := perlinCellAt(to)
+	res, err := w.pc.Find(gridpath.Position{X: fx, Y: fy},
+		gridpath.Position{X: tx, Y: ty}, gridpath.ASTAR)
+	if err != nil {
+		return nil, err
+	}
+	path := []Point2D{to}
+	for i := len(res.Path) - 2; i > 0; i-- {
+		path = append(path, perlinCellCenter(res.Path[i].X, res.Path[i].Y))
+	}
+	return path, nil
+}
+
+// MoveEntity steps the entity one unit toward the last point of its path,
+// dropping points as they're reached
 func (w *World) MoveEntity() {
-	if w.e != nil && w.e.moveTarget != nil && w.e.path != nil {
-		if len(w.e.path) == 0 {
-			w.e.moveTarget = nil
-			w.e.path = nil
-		}
-		last_ix := len(w.e.path) - 1
-		var target Point2D
-		var dx, dy, d float64
-		var validTarget = false
-		for !validTarget {
-			target = w.e.path[last_ix]
-			dx = float64(target.X - w.e.pos.X)
-			dy = float64(target.Y - w.e.pos.Y)
-			d = math.Sqrt(dx*dx + dy*dy)
-			reached := (d <= 2)
-			if reached {
-				w.e.path = w.e.path[:last_ix]
-				last_ix--
-			} else {
-				validTarget = true
-			}
+	if w.e == nil || w.e.moveTarget == nil || w.e.path == nil {
+		return
+	}
+	for len(w.e.path) > 0 {
+		target := w.e.path[len(w.e.path)-1]
+		dx, dy, d := Distance(w.e.pos, target)
+		if d > 2 {
+			w.e.pos.X += int(math.Round(dx / d))
+			w.e.pos.Y += int(math.Round(dy / d))
+			return
 		}
-		w.e.pos.X += int(math.Max(1, dx/d))
-		w.e.pos.Y += int(math.Max(1, dy/d))
+		w.e.path = w.e.path[:len(w.e.path)-1]
 	}
+	w.e.moveTarget = nil
+	w.e.path = nil
 }
@@ -38,6 +38,13 @@ func handleQuit(e sdl.Event) bool {
 	return true
 }
 
+func reportPath(ms float64, err error) {
+	fmt.Printf("path calculation took %.3f ms\n", ms)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func doRegen(w *World, keh KeyEventHandler) {
 	if _, ok := keh.once[sdl.K_g]; !ok {
 		keh.once[sdl.K_g] = &sync.Once{}
@@ -48,9 +55,9 @@ func doRegen(w *World, keh KeyEventHandler) {
 		keh.once[sdl.K_g].Do(func() {
 			w.mapMutex.Lock()
 			w.RegenMap()
-			ms := w.ComputePath()
+			ms, err := w.ComputePath()
 			w.mapMutex.Unlock()
-			fmt.Printf("path calculation took %.3f ms\n", ms)
+			reportPath(ms, err)
 			keh.mutex[sdl.K_g].Lock()
 			time.Sleep(time.Second)
 			keh.once[sdl.K_g] = &sync.Once{}
@@ -107,8 +114,7 @@ func handleMouseEvents(w *World, e sdl.Event) {
 			if me.Button == sdl.BUTTON_RIGHT {
 				if w.e != nil {
 					w.e.moveTarget = &pos
-					ms := w.ComputePath()
-					fmt.Printf("path calculation took %.3f ms\n", ms)
+					reportPath(w.ComputePath())
 				}
 			}
 		}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
+)
+
+// the world-space centre of perlin cell px, py
+func perlinCellCenter(px int, py int) Point2D {
+	return Point2D{
+		int(float64(px)*PSCALE + PSCALE/2),
+		int(float64(py)*PSCALE + PSCALE/2)}
+}
+
+// the perlin cell containing world-space p
+func perlinCellAt(p Point2D) (px int, py int) {
+	px = int(float64(p.X) / PSCALE)
+	py = int(float64(p.Y) / PSCALE)
+	if px < 0 {
+		px = 0
+	} else if px > PW-1 {
+		px = PW - 1
+	}
+	if py < 0 {
+		py = 0
+	} else if py > PH-1 {
+		py = PH - 1
+	}
+	return px, py
+}
+
+// mark the perlin cells whose centres lie in a lake
+func (wm *WorldMap) buildLakeMask() {
+	wm.lakeMask = make([][]bool, PH)
+	for py := 0; py < PH; py++ {
+		wm.lakeMask[py] = make([]bool, PW)
+		for px := 0; px < PW; px++ {
+			c := perlinCellCenter(px, py)
+			for _, l := range wm.Lakes {
+				if l.containsPoint2D(c) {
+					wm.lakeMask[py][px] = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// trace rivers down the perlin from wherever enough of it drains through,
+// until they reach a lake or run off the edge of the map, and rasterize
+// their widths for the terrain grid. Needs the lakes made first
+func (wm *WorldMap) makeRivers() {
+	wm.buildLakeMask()
+	down, filled := heightfield.Drainage(wm.perlin)
+	wm.flow = heightfield.FlowAccumulation(down, filled)
+	wm.Rivers = heightfield.Rivers(down, wm.flow, RIVER_PARAMS,
+		func(px int, py int) bool {
+			return wm.lakeMask[py][px]
+		})
+	wm.riverWidth = heightfield.RiverWidths(wm.Rivers, PW, PH)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+)
+
+// TerrainGrid is the world map at perlin resolution as a gridpath.Grid,
+// indexed x, y by perlin cell: lakes can't be entered, and river cells cost
+// more to enter the wider the river, so paths cross rivers where they're
+// narrow rather than wading along them
+type TerrainGrid struct {
+	wm *WorldMap
+}
+
+func (g *TerrainGrid) InGrid(x int, y int) bool {
+	return x >= 0 && x < PW && y >= 0 && y < PH
+}
+
+func (g *TerrainGrid) Passable(x int, y int) bool {
+	return !g.wm.lakeMask[y][x]
+}
+
+func (g *TerrainGrid) Cost(x int, y int) int {
+	return 1 + int(RIVER_CROSSING_COST*g.wm.riverWidth[y][x])
+}
+
+func (g *TerrainGrid) Neighbors(
+	p gridpath.Position, buf []gridpath.Position) []gridpath.Position {
+	return gridpath.AppendNeighbors(g, p, buf, false)
+}
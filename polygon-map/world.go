@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 	"github.com/veandco/go-sdl2/sdl"
 	"sync"
 )
@@ -10,9 +11,10 @@ type World struct {
 	mapMutex    sync.Mutex
 	e           *Entity
 	entityMutex sync.Mutex
-	c           *PathCalculator
 	r           *sdl.Renderer
 	param       int
+	// A* over the perlin cells' TerrainGrid, for the entity's paths
+	pc *gridpath.PathComputer
 }
 
 func NewWorld(r *sdl.Renderer) *World {
@@ -20,7 +22,7 @@ func NewWorld(r *sdl.Renderer) *World {
 	w.r = r
 	w.param = 0
 	w.m = GenerateWorldMap(r)
-	w.c = NewPathCalculator(w.m)
+	w.pc = gridpath.NewPathComputer(&TerrainGrid{w.m}, PW, PH)
 	return &w
 }
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
 	"github.com/veandco/go-sdl2/sdl"
 	"math"
 	"sort"
@@ -16,6 +17,16 @@ type WorldMap struct {
 	minima        []Point2D
 	seed          int64
 	param         int
+	// where erosion of perlin left sediment, scaled to [0, 1]
+	sediment [][]float64
+	// rivers traced over the perlin, in perlin cells
+	Rivers []heightfield.River
+	// the number of perlin cells draining through each perlin cell
+	flow [][]float64
+	// the width of river over each perlin cell (0 where it's dry)
+	riverWidth [][]float64
+	// whether each perlin cell's centre is in a lake
+	lakeMask [][]bool
 }
 
 func GenerateWorldMap(r *sdl.Renderer) *WorldMap {
@@ -31,6 +42,7 @@ func GenerateWorldMap(r *sdl.Renderer) *WorldMap {
 	m.findMinima()
 	fmt.Println("finished finding minima")
 	m.makeLakes()
+	m.makeRivers()
 	return &m
 }
 
@@ -146,6 +158,7 @@ func (wm *WorldMap) Regen(param int) {
 			l.highlighted = true
 		}
 	}
+	wm.makeRivers()
 }
 
 func (wm *WorldMap) generatePerlin() {
@@ -168,6 +181,22 @@ func (wm *WorldMap) generatePerlin() {
 		}
 	})
 	wm.perlin = SmoothPerlin(combined)
+	wm.sediment = heightfield.New(PW, PH)
+	if EROSION_DROPLETS_PER_CELL > 0 {
+		ep := heightfield.DEFAULT_EROSION
+		ep.Hydraulic.Droplets = int(EROSION_DROPLETS_PER_CELL * float64(PW*PH))
+		ep.Hydraulic.Seed = wm.seed
+		ep.Thermal.Seed = wm.seed
+		wm.sediment = heightfield.Erode(wm.perlin, ep)
+		heightfield.Normalize(wm.sediment)
+		// the texture's brightness is the height, which deposits could
+		// push past 1
+		for _, row := range wm.perlin {
+			for x := range row {
+				row[x] = math.Max(0, math.Min(1, row[x]))
+			}
+		}
+	}
 }
 
 func (wm *WorldMap) findMinima() {
@@ -0,0 +1,130 @@
+package simclock
+
+import (
+	"time"
+)
+
+// where a Clock gets the current time from
+type TimeSource interface {
+	Now() time.Time
+}
+
+// the wall clock
+type RealTime struct{}
+
+func (RealTime) Now() time.Time {
+	return time.Now()
+}
+
+// a time source which only moves when told to, for tests and replays
+type ManualTime struct {
+	T time.Time
+}
+
+func (m *ManualTime) Now() time.Time {
+	return m.T
+}
+
+func (m *ManualTime) Advance(d time.Duration) {
+	m.T = m.T.Add(d)
+}
+
+// a function run once every `every` ticks
+type task struct {
+	every int
+	f     func(tick int)
+}
+
+// Dt:			the fixed length of a tick
+// MaxSteps:	the most ticks one call to Update will run (the rest is dropped)
+// Tick:		the number of ticks run so far
+// src:			where Update reads elapsed time from
+// last:		the time Update last read from src
+// acc:			elapsed time not yet consumed by a tick
+// tasks:		run on every tick whose number is a multiple of their period
+type Clock struct {
+	Dt       time.Duration
+	MaxSteps int
+	Tick     int
+	src      TimeSource
+	last     time.Time
+	acc      time.Duration
+	tasks    []task
+}
+
+func New(dt time.Duration, src TimeSource) *Clock {
+	return &Clock{
+		Dt:       dt,
+		MaxSteps: 8,
+		src:      src,
+		last:     src.Now(),
+	}
+}
+
+// run f on every tick whose number is a multiple of every (on every tick if
+// every < 1), in the order the tasks were added
+func (c *Clock) Every(every int, f func(tick int)) {
+	if every < 1 {
+		every = 1
+	}
+	c.tasks = append(c.tasks, task{every, f})
+}
+
+// run f every period, rounded to the nearest whole number of ticks
+func (c *Clock) EveryDuration(period time.Duration, f func(tick int)) {
+	c.Every(c.Ticks(period), f)
+}
+
+// the number of ticks (at least 1) nearest to d
+func (c *Clock) Ticks(d time.Duration) int {
+	n := int((d + c.Dt/2) / c.Dt)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// run a single tick, regardless of elapsed time
+func (c *Clock) Step() {
+	for _, t := range c.tasks {
+		if c.Tick%t.every == 0 {
+			t.f(c.Tick)
+		}
+	}
+	c.Tick++
+}
+
+// run as many ticks as the time elapsed since the last call to Update (or
+// Skip) covers, keeping the remainder for next time. Returns the number of
+// ticks run
+func (c *Clock) Update() int {
+	now := c.src.Now()
+	c.acc += now.Sub(c.last)
+	c.last = now
+	if max := time.Duration(c.MaxSteps) * c.Dt; c.acc > max {
+		c.acc = max
+	}
+	n := 0
+	for c.acc >= c.Dt {
+		c.Step()
+		c.acc -= c.Dt
+		n++
+	}
+	return n
+}
+
+// forget time elapsed since the last call to Update (eg. while paused)
+func (c *Clock) Skip() {
+	c.last = c.src.Now()
+	c.acc = 0
+}
+
+// how far into the next tick the accumulated time is, in [0, 1)
+func (c *Clock) Alpha() float64 {
+	return float64(c.acc) / float64(c.Dt)
+}
+
+// how long until the next tick is due
+func (c *Clock) UntilNext() time.Duration {
+	return c.Dt - c.acc - c.src.Now().Sub(c.last)
+}
@@ -0,0 +1,70 @@
+package simclock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateAccumulates(t *testing.T) {
+	src := &ManualTime{}
+	c := New(10*time.Millisecond, src)
+	var ticks []int
+	c.Every(1, func(tick int) { ticks = append(ticks, tick) })
+
+	src.Advance(25 * time.Millisecond)
+	if n := c.Update(); n != 2 {
+		t.Fatalf("expected 2 ticks for 25ms, ran %d", n)
+	}
+	if a := c.Alpha(); a < 0.49 || a > 0.51 {
+		t.Fatalf("expected alpha 0.5, got %f", a)
+	}
+	src.Advance(5 * time.Millisecond)
+	if n := c.Update(); n != 1 {
+		t.Fatalf("expected the leftover 5ms to make up a tick, ran %d", n)
+	}
+	if len(ticks) != 3 || ticks[0] != 0 || ticks[2] != 2 {
+		t.Fatalf("unexpected ticks %v", ticks)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	c := New(8*time.Millisecond, &ManualTime{})
+	var order []string
+	c.Every(1, func(int) { order = append(order, "move") })
+	c.EveryDuration(16*time.Millisecond, func(int) { order = append(order, "diffuse") })
+	c.Every(3, func(int) { order = append(order, "draw") })
+	for i := 0; i < 4; i++ {
+		c.Step()
+	}
+	expected := []string{
+		"move", "diffuse", "draw",
+		"move",
+		"move", "diffuse",
+		"move", "draw"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i := range order {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestMaxStepsAndSkip(t *testing.T) {
+	src := &ManualTime{}
+	c := New(10*time.Millisecond, src)
+	c.MaxSteps = 4
+	src.Advance(time.Second)
+	if n := c.Update(); n != 4 {
+		t.Fatalf("expected a stall to be capped at 4 ticks, ran %d", n)
+	}
+	src.Advance(time.Second)
+	c.Skip()
+	if n := c.Update(); n != 0 {
+		t.Fatalf("expected skipped time to be dropped, ran %d ticks", n)
+	}
+	if c.Tick != 4 {
+		t.Fatalf("expected to be on tick 4, on %d", c.Tick)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/dt-rush/gamedev-sketchbook/simclock
+
+go 1.19
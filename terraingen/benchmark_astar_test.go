@@ -8,17 +8,17 @@ import (
 
 func BenchmarkAstar(b *testing.B) {
 	rand.Seed(time.Now().UnixNano())
-	w := NewWorld()
+	w := NewWorld(WORLD_CELLWIDTH, WORLD_CELLHEIGHT)
 	N := 1024 * 16
 	positions := make([]PositionPair, N)
 	for i, _ := range positions {
 		positions[i] = PositionPair{
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)},
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)},
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)}}
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)}}
 	}
 	b.ResetTimer()
 	for i := 0; i < 1024*16; i++ {
@@ -29,48 +29,98 @@ func BenchmarkAstar(b *testing.B) {
 	}
 }
 
-func BenchmarkAstarUnrolled(b *testing.B) {
+func BenchmarkAstarHandRolled(b *testing.B) {
 	rand.Seed(time.Now().UnixNano())
-	w := NewWorld()
+	w := NewWorld(WORLD_CELLWIDTH, WORLD_CELLHEIGHT)
 	N := 1024 * 16
 	positions := make([]PositionPair, N)
 	for i, _ := range positions {
 		positions[i] = PositionPair{
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)},
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)},
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)}}
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)}}
 	}
 	b.ResetTimer()
 	for i := 0; i < 1024*16; i++ {
 		w.e = &Entity{
 			pos:        positions[i].p1,
 			moveTarget: &positions[i].p2}
-		w.ComputeEntityPathUnrolled()
+		w.ComputeEntityPathHandRolled()
 	}
 }
 
-func BenchmarkAstarHandRolled(b *testing.B) {
+func BenchmarkAstarHPA(b *testing.B) {
 	rand.Seed(time.Now().UnixNano())
-	w := NewWorld()
+	w := NewWorld(WORLD_CELLWIDTH, WORLD_CELLHEIGHT)
 	N := 1024 * 16
 	positions := make([]PositionPair, N)
 	for i, _ := range positions {
 		positions[i] = PositionPair{
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)},
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)},
 			Position{
-				rand.Intn(WORLD_CELLWIDTH),
-				rand.Intn(WORLD_CELLHEIGHT)}}
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)}}
 	}
 	b.ResetTimer()
-	for i := 0; i < 1024*16; i++ {
+	for i := 0; i < b.N; i++ {
 		w.e = &Entity{
-			pos:        positions[i].p1,
-			moveTarget: &positions[i].p2}
-		w.ComputeEntityPathHandRolled()
+			pos:        positions[i%N].p1,
+			moveTarget: &positions[i%N].p2}
+		w.ComputeEntityPathHPA()
+	}
+}
+
+func BenchmarkAstarTheta(b *testing.B) {
+	rand.Seed(time.Now().UnixNano())
+	w := NewWorld(WORLD_CELLWIDTH, WORLD_CELLHEIGHT)
+	N := 1024 * 16
+	positions := make([]PositionPair, N)
+	for i, _ := range positions {
+		positions[i] = PositionPair{
+			Position{
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)},
+			Position{
+				X: rand.Intn(WORLD_CELLWIDTH),
+				Y: rand.Intn(WORLD_CELLHEIGHT)}}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.e = &Entity{
+			pos:        positions[i%N].p1,
+			moveTarget: &positions[i%N].p2}
+		w.ComputeEntityPathTheta()
 	}
 }
+
+// on a map in the thousands-of-cells range, compare the full-map search
+// with HPA*
+func benchmarkLargeMap(b *testing.B, dim int, hpa bool) {
+	rand.Seed(time.Now().UnixNano())
+	w := NewWorld(dim, dim)
+	positions := make([]PositionPair, 256)
+	for i, _ := range positions {
+		positions[i] = PositionPair{
+			Position{X: rand.Intn(dim), Y: rand.Intn(dim)},
+			Position{X: rand.Intn(dim), Y: rand.Intn(dim)}}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.e = &Entity{
+			pos:        positions[i%len(positions)].p1,
+			moveTarget: &positions[i%len(positions)].p2}
+		if hpa {
+			w.ComputeEntityPathHPA()
+		} else {
+			w.ComputeEntityPathHandRolled()
+		}
+	}
+}
+
+func BenchmarkLargeMapHandRolled(b *testing.B) { benchmarkLargeMap(b, 128, false) }
+func BenchmarkLargeMapHPA(b *testing.B)        { benchmarkLargeMap(b, 128, true) }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/veandco/go-sdl2/sdl"
+	"io"
+	"os"
+)
+
+// a kind of terrain, picked by a cell's generated elevation, moisture and
+// temperature (see BiomeTable.Classify).
+//
+// Name:		what the biome is called (unique in its table)
+// Max:			elevations below this (and not in an earlier biome) can be this biome
+// Moisture:	if set, the [min, max] moisture of the biome (otherwise any)
+// Temperature:	if set, the [min, max] temperature of the biome (otherwise any)
+// Cost:		terrain cost of stepping into the cell, for pathfinding
+// Glyph:		the character WorldMap.Print shows the cell as
+// Color:		the cell's colour at height 0
+// Shade:		added to Color for each 0.1 of height
+// Banded:		if true, Shade is added only for whole 0.1 bands (water depth)
+// Density:		if true, cells carry their height as CellData.Density
+// Data:		any other per-biome values, shared by all its cells as CellData.Props
+type Biome struct {
+	Name        string             `json:"name"`
+	Max         float64            `json:"max"`
+	Moisture    *[2]float64        `json:"moisture"`
+	Temperature *[2]float64        `json:"temperature"`
+	Cost        int                `json:"cost"`
+	Glyph       string             `json:"glyph"`
+	Color       [3]float64         `json:"color"`
+	Shade       [3]float64         `json:"shade"`
+	Banded      bool               `json:"banded"`
+	Density     bool               `json:"density"`
+	Data        map[string]float64 `json:"data"`
+}
+
+// whether v lies in r (any v, if r is nil)
+func inRange(r *[2]float64, v float64) bool {
+	return r == nil || (v >= r[0] && v <= r[1])
+}
+
+// the colour of a cell of the biome at height v
+func (b *Biome) ColorAt(v float64) sdl.Color {
+	steps := v / 0.1
+	if b.Banded {
+		steps = float64(int(steps))
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		c := b.Color[i] + b.Shade[i]*steps
+		if c < 0 {
+			c = 0
+		}
+		if c > 255 {
+			c = 255
+		}
+		rgb[i] = uint8(c)
+	}
+	return sdl.Color{R: rgb[0], G: rgb[1], B: rgb[2]}
+}
+
+// the biomes a map is made of, lowest first. A cell's kind is the index of
+// its biome. Biomes sharing a Max divide that band of elevation between
+// them by climate, Whittaker-style
+type BiomeTable struct {
+	Biomes []Biome `json:"biomes"`
+}
+
+//go:embed biomes.json
+var defaultBiomesJSON []byte
+
+// water and beaches, a Whittaker diagram of climates (desert to rainforest,
+// tundra to forest) over the lowlands, and mountains topped with snow
+var DEFAULT_BIOMES = mustParseBiomes(defaultBiomesJSON)
+
+func mustParseBiomes(data []byte) *BiomeTable {
+	t, err := ParseBiomes(data)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func LoadBiomes(path string) (*BiomeTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBiomes(f)
+}
+
+func ReadBiomes(r io.Reader) (*BiomeTable, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBiomes(data)
+}
+
+func ParseBiomes(data []byte) (*BiomeTable, error) {
+	var t BiomeTable
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("biome table: %v", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks the table can be generated from: it has biomes, with
+// unique names, single-character glyphs, positive costs, Max never falling
+// and ranges with min <= max
+func (t *BiomeTable) Validate() error {
+	if len(t.Biomes) == 0 {
+		return fmt.Errorf("biome table is empty")
+	}
+	names := make(map[string]bool)
+	for i, b := range t.Biomes {
+		if b.Name == "" || names[b.Name] {
+			return fmt.Errorf("biome %d: missing or repeated name %q", i, b.Name)
+		}
+		names[b.Name] = true
+		if len([]rune(b.Glyph)) != 1 {
+			return fmt.Errorf("biome %q: glyph %q isn't one character",
+				b.Name, b.Glyph)
+		}
+		if b.Cost < 1 {
+			return fmt.Errorf("biome %q: cost %d is less than 1", b.Name, b.Cost)
+		}
+		if i > 0 && b.Max < t.Biomes[i-1].Max {
+			return fmt.Errorf("biome %q: max %g is below %q's %g",
+				b.Name, b.Max, t.Biomes[i-1].Name, t.Biomes[i-1].Max)
+		}
+		for _, r := range []*[2]float64{b.Moisture, b.Temperature} {
+			if r != nil && r[0] > r[1] {
+				return fmt.Errorf("biome %q: range %v is backwards", b.Name, *r)
+			}
+		}
+	}
+	return nil
+}
+
+// the kind of the named biome, or -1
+func (t *BiomeTable) Kind(name string) int {
+	for i, b := range t.Biomes {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Classify gives the kind of a cell from its elevation, moisture and
+// temperature (all nominally in [0, 1]): the first biome whose Max is above
+// the elevation and whose ranges hold the moisture and temperature. If none
+// matches, it's the last biome
+func (t *BiomeTable) Classify(
+	elevation float64, moisture float64, temperature float64) int {
+	for i := range t.Biomes {
+		b := &t.Biomes[i]
+		if elevation < b.Max && inRange(b.Moisture, moisture) &&
+			inRange(b.Temperature, temperature) {
+			return i
+		}
+	}
+	return len(t.Biomes) - 1
+}
+
+// an elevation in the middle of the biome's band
+func (t *BiomeTable) TypicalHeight(kind int) float64 {
+	hi := t.Biomes[kind].Max
+	lo := 0.0
+	for i := kind - 1; i >= 0; i-- {
+		if t.Biomes[i].Max < hi {
+			lo = t.Biomes[i].Max
+			break
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultBiomes(t *testing.T) {
+	b := DEFAULT_BIOMES
+	if b.Biomes[0].Name != "water" || b.Biomes[0].Max != 0.4 ||
+		b.Biomes[0].Cost != 100 || b.Biomes[0].Glyph != "o" {
+		t.Fatalf("biome 0 is %+v", b.Biomes[0])
+	}
+	m := GenerateWorldMap(4, 4, 1, DEFAULT_GEN_PARAMS)
+	// the colours the cells had before the table
+	water := m.BiomeCell(b.Kind("water"), 0.25)
+	if water.color.B != 48+16*2 {
+		t.Fatalf("water at depth 2 is %v", water.color)
+	}
+	forest := m.BiomeCell(b.Kind("forest"), 0.6)
+	if forest.color.G != uint8(180-(0.6/0.1)*16) {
+		t.Fatalf("forest at 0.6 is %v", forest.color)
+	}
+	if d := forest.data.(CellData); d.Density != 0.6 {
+		t.Fatalf("forest density %g", d.Density)
+	}
+	for kind := range b.Biomes {
+		if c := m.KindCell(kind); c.kind != kind {
+			t.Fatalf("KindCell(%d) made a %d", kind, c.kind)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		e, m, temp float64
+		want       string
+	}{
+		// elevation alone decides the water, beaches and peaks
+		{0, 1, 1, "water"},
+		{0.39, 0, 0, "water"},
+		{0.42, 0.5, 0.5, "sand"},
+		{0.8, 0.5, 0.9, "mountain"},
+		{1, 0, 0, "snow"},
+		// the lowlands follow the Whittaker diagram
+		{0.6, 0.1, 0.9, "desert"},
+		{0.6, 0.5, 0.9, "savanna"},
+		{0.6, 0.9, 0.9, "rainforest"},
+		{0.6, 0.2, 0.1, "tundra"},
+		{0.6, 0.8, 0.1, "taiga"},
+		{0.6, 0.3, 0.5, "grass"},
+		{0.6, 0.8, 0.5, "forest"},
+	}
+	b := DEFAULT_BIOMES
+	for _, c := range cases {
+		k := b.Classify(c.e, c.m, c.temp)
+		if b.Biomes[k].Name != c.want {
+			t.Fatalf("elevation %g, moisture %g, temperature %g is %s, want %s",
+				c.e, c.m, c.temp, b.Biomes[k].Name, c.want)
+		}
+	}
+}
+
+func TestLoadBiomes(t *testing.T) {
+	m, err := LoadBiomes("biomes_mountains.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mountain := m.Kind("mountain")
+	if mountain < 0 || m.Biomes[mountain].Cost != 200 {
+		t.Fatal("no mountains in biomes_mountains.json")
+	}
+	swamp := m.Biomes[m.Kind("swamp")]
+	if swamp.Data["depth"] != 0.3 {
+		t.Fatalf("swamp data %v", swamp.Data)
+	}
+	p := DEFAULT_GEN_PARAMS
+	p.Biomes = m
+	wm := GenerateWorldMap(16, 16, 1, p)
+	wm.SetCell(Position{X: 0, Y: 0}, wm.KindCell(mountain))
+	if wm.Cost(0, 0) != 200 || wm.CellAt(Position{X: 0, Y: 0}).rep != "^" {
+		t.Fatal("mountain cell doesn't cost or print as a mountain")
+	}
+
+	bad := []string{
+		`{"biomes": []}`,
+		`{"biomes": [{"name": "a", "max": 1, "cost": 1, "glyph": "ab"}]}`,
+		`{"biomes": [{"name": "a", "max": 1, "cost": 0, "glyph": "a"}]}`,
+		`{"biomes": [{"name": "a", "max": 1, "cost": 1, "glyph": "a"},
+			{"name": "b", "max": 0.5, "cost": 1, "glyph": "b"}]}`,
+		`{"biomes": [{"name": "a", "max": 0.5, "cost": 1, "glyph": "a"},
+			{"name": "a", "max": 1, "cost": 1, "glyph": "b"}]}`,
+		`{"biomes": [`,
+	}
+	for _, s := range bad {
+		if _, err := ReadBiomes(strings.NewReader(s)); err == nil {
+			t.Fatalf("accepted %s", s)
+		}
+	}
+}
@@ -1,16 +1,24 @@
 package main
 
+import (
+	"time"
+)
+
 const WORLD_CELLDIMENSION = 24
 const WORLD_CELLHEIGHT = 24
 const WORLD_CELLWIDTH = WORLD_CELLDIMENSION
 
+// width and height in cells of the sectors HPA* chunks the map into
+const HPA_CLUSTERSIZE = 8
+
 const WORLD_HEIGHT = 1048
 const WORLD_WIDTH = 1048
 
 const WINDOW_HEIGHT = 640
 const WINDOW_WIDTH = 640
 
-const WORLD_CELL_PIXEL_HEIGHT = float64(WINDOW_HEIGHT) / float64(WORLD_CELLHEIGHT)
-const WORLD_CELL_PIXEL_WIDTH = float64(WINDOW_WIDTH) / float64(WORLD_CELLWIDTH)
-
 const FPS = 60
+
+// length of a simulation tick, and how often the entity steps along its path
+const SIM_DT = 10 * time.Millisecond
+const MOVE_PERIOD = 50 * time.Millisecond
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
+	"time"
+)
+
+// the parameters of one perlin noise layer (see PerlinNoiseInt2D)
+//
+// Scale:		cells per unit of noise space (larger is smoother)
+// Alpha:		weight of each octave relative to the next
+// Beta:		frequency of each octave relative to the last
+// Octaves:		number of octaves summed
+// SeedOffset:	added to the map's seed to seed this layer, so layers differ
+type NoiseParams struct {
+	Scale      float64
+	Alpha      float64
+	Beta       float64
+	Octaves    int
+	SeedOffset int64
+}
+
+// the layer's noise over a w x h map generated from seed
+func (p NoiseParams) Field(w int, h int, seed int64) [][]float64 {
+	return PerlinNoiseInt2D(w, h, p.Scale, p.Alpha, p.Beta, p.Octaves,
+		seed+p.SeedOffset)
+}
+
+// everything GenerateWorldMap's output depends on, besides the seed and the
+// map size.
+//
+// Elevation:	the elevation noise
+// Moisture:	the moisture noise
+// Temperature:	the temperature noise
+// Lapse:		how much colder a cell is for each unit of elevation above 0.5
+// Erosion:		if set, how the elevation is eroded (its seeds are offsets from the map's)
+// SedimentMoisture:	how much wetter the cells erosion left the most sediment in are
+// Biomes:		the biome each elevation and climate is, and how it looks and costs
+type GenParams struct {
+	Elevation        NoiseParams
+	Moisture         NoiseParams
+	Temperature      NoiseParams
+	Lapse            float64
+	Erosion          *heightfield.ErosionParams
+	SedimentMoisture float64
+	Biomes           *BiomeTable
+}
+
+func clamp01(x float64) float64 {
+	if x < 0 {
+		return 0
+	} else if x > 1 {
+		return 1
+	} else {
+		return x
+	}
+}
+
+// stretch noise from PerlinNoiseInt2D (which stays within about 0.25 to
+// 1.75, centred on 1) over [0, 1]
+func stretch(v float64) float64 {
+	return clamp01((v - 0.25) / 1.5)
+}
+
+var DEFAULT_GEN_PARAMS = GenParams{
+	Elevation: NoiseParams{
+		Scale: 16, Alpha: 2.0, Beta: 2.0, Octaves: 3, SeedOffset: 0},
+	Moisture: NoiseParams{
+		Scale: 24, Alpha: 2.0, Beta: 2.0, Octaves: 3, SeedOffset: 1},
+	Temperature: NoiseParams{
+		Scale: 48, Alpha: 2.0, Beta: 2.0, Octaves: 2, SeedOffset: 2},
+	Lapse:            0.5,
+	SedimentMoisture: 0.3,
+	Biomes:           DEFAULT_BIOMES,
+}
+
+// a seed for when the caller doesn't care which map they get
+func RandomSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// GenerateWorldMap builds a w x h map from seed. The same seed and params
+// always give the same map, down to the byte (see WorldMap.Encode)
+func GenerateWorldMap(w int, h int, seed int64, p GenParams) *WorldMap {
+	m := WorldMap{w: w, h: h, seed: seed, params: p}
+	m.cells = make([][]WorldMapCell, h)
+	for y := 0; y < h; y++ {
+		m.cells[y] = make([]WorldMapCell, w)
+	}
+	m.elevation = MapPerlin(p.Elevation.Field(m.w, m.h, m.seed), stretch)
+	m.sediment = heightfield.New(m.w, m.h)
+	if p.Erosion != nil {
+		ep := *p.Erosion
+		ep.Hydraulic.Seed += m.seed
+		ep.Thermal.Seed += m.seed
+		m.sediment = heightfield.Erode(m.elevation, ep)
+		heightfield.Normalize(m.sediment)
+		m.elevation = MapPerlin(m.elevation, clamp01)
+	}
+	m.moisture = OpPerlins(p.Moisture.Field(m.w, m.h, m.seed), m.sediment,
+		func(v float64, s float64) float64 {
+			return clamp01(stretch(v) + p.SedimentMoisture*s)
+		})
+	m.temperature = OpPerlins(p.Temperature.Field(m.w, m.h, m.seed),
+		m.elevation, func(v float64, e float64) float64 {
+			return clamp01(stretch(v) - p.Lapse*(e-0.5))
+		})
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			e := m.elevation[y][x]
+			kind := p.Biomes.Classify(
+				e, m.moisture[y][x], m.temperature[y][x])
+			c := m.BiomeCell(kind, e)
+			c.pos = Position{X: x, Y: y}
+			m.cells[y][x] = c
+		}
+	}
+	return &m
+}
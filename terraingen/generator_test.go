@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
+	"reflect"
+	"testing"
+)
+
+func encode(t *testing.T, m *WorldMap) []byte {
+	var buf bytes.Buffer
+	if err := m.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	const seed = 1529124576499821233
+	a := encode(t, GenerateWorldMap(48, 32, seed, DEFAULT_GEN_PARAMS))
+	b := encode(t, GenerateWorldMap(48, 32, seed, DEFAULT_GEN_PARAMS))
+	if !bytes.Equal(a, b) {
+		t.Fatal("same seed and params gave different maps")
+	}
+	c := encode(t, GenerateWorldMap(48, 32, seed+1, DEFAULT_GEN_PARAMS))
+	if bytes.Equal(a[24:], c[24:]) {
+		t.Fatal("different seeds gave the same cells")
+	}
+}
+
+// water, sand, grass and forest by elevation alone
+const SIMPLE_BIOMES = `{"biomes": [
+	{"name": "water", "max": 0.4, "cost": 100, "glyph": "o"},
+	{"name": "sand", "max": 0.45, "cost": 1, "glyph": "."},
+	{"name": "grass", "max": 0.55, "cost": 1, "glyph": "."},
+	{"name": "forest", "max": 1, "cost": 40, "glyph": "#", "density": true}
+]}`
+
+func count(m *WorldMap, name string) int {
+	kind := m.params.Biomes.Kind(name)
+	n := 0
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			if m.cells[y][x].kind == kind {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestGenerateThresholds(t *testing.T) {
+	const seed = 1
+	withMax := func(maxes ...float64) GenParams {
+		p := DEFAULT_GEN_PARAMS
+		p.Biomes = mustParseBiomes([]byte(SIMPLE_BIOMES))
+		for i, max := range maxes {
+			p.Biomes.Biomes[i].Max = max
+		}
+		return p
+	}
+	base := GenerateWorldMap(32, 32, seed, withMax())
+	p := withMax(-0.3, -0.2, -0.1)
+	if m := GenerateWorldMap(32, 32, seed, p); count(m, "forest") != 32*32 {
+		t.Fatal("thresholds below 0 didn't give all forest")
+	}
+	p = withMax(0.5, 0.55, 0.65)
+	if m := GenerateWorldMap(32, 32, seed, p); count(m, "water") <=
+		count(base, "water") {
+		t.Fatal("raising the water level didn't add water")
+	}
+	// with flat elevation noise every cell is at 0.5
+	p = withMax()
+	p.Elevation.Octaves = 0
+	if m := GenerateWorldMap(32, 32, seed, p); count(m, "grass") != 32*32 {
+		t.Fatal("flat elevation didn't give all grass")
+	}
+}
+
+func TestGenerateLayers(t *testing.T) {
+	const seed = 5
+	base := GenerateWorldMap(32, 32, seed, DEFAULT_GEN_PARAMS)
+	p := DEFAULT_GEN_PARAMS
+	p.Moisture.SeedOffset = 100
+	m := GenerateWorldMap(32, 32, seed, p)
+	if !reflect.DeepEqual(m.elevation, base.elevation) ||
+		!reflect.DeepEqual(m.temperature, base.temperature) {
+		t.Fatal("moving the moisture seed changed the other layers")
+	}
+	if reflect.DeepEqual(m.moisture, base.moisture) {
+		t.Fatal("moving the moisture seed didn't change the moisture")
+	}
+	p = DEFAULT_GEN_PARAMS
+	p.Elevation.SeedOffset = 100
+	m = GenerateWorldMap(32, 32, seed, p)
+	if reflect.DeepEqual(m.elevation, base.elevation) ||
+		!reflect.DeepEqual(m.moisture, base.moisture) {
+		t.Fatal("moving the elevation seed didn't move just the elevation")
+	}
+	for _, layer := range [][][]float64{
+		base.elevation, base.moisture, base.temperature} {
+		for _, row := range layer {
+			for _, v := range row {
+				if v < 0 || v > 1 {
+					t.Fatalf("layer value %g is outside [0, 1]", v)
+				}
+			}
+		}
+	}
+	// with flat temperature noise, the highest cell is the coldest
+	p = DEFAULT_GEN_PARAMS
+	p.Temperature.Octaves = 0
+	m = GenerateWorldMap(32, 32, seed, p)
+	var hi, lo Position
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if m.elevation[y][x] > m.elevation[hi.Y][hi.X] {
+				hi = Position{X: x, Y: y}
+			}
+			if m.elevation[y][x] < m.elevation[lo.Y][lo.X] {
+				lo = Position{X: x, Y: y}
+			}
+		}
+	}
+	if m.temperature[hi.Y][hi.X] >= m.temperature[lo.Y][lo.X] {
+		t.Fatal("higher ground isn't colder")
+	}
+}
+
+func TestGenerateUsesEveryClimate(t *testing.T) {
+	m := GenerateWorldMap(128, 128, 3, DEFAULT_GEN_PARAMS)
+	seen := 0
+	for _, b := range m.params.Biomes.Biomes {
+		if count(m, b.Name) > 0 {
+			seen++
+		}
+	}
+	if seen < len(m.params.Biomes.Biomes)/2 {
+		t.Fatalf("only %d biomes on a 128x128 map", seen)
+	}
+}
+
+func TestGenerateEroded(t *testing.T) {
+	const seed = 9
+	base := GenerateWorldMap(48, 48, seed, DEFAULT_GEN_PARAMS)
+	ep := heightfield.DEFAULT_EROSION
+	ep.Hydraulic.Droplets = 48 * 48 * 2
+	p := DEFAULT_GEN_PARAMS
+	p.Erosion = &ep
+	a := GenerateWorldMap(48, 48, seed, p)
+	if !bytes.Equal(encode(t, a), encode(t, GenerateWorldMap(48, 48, seed, p))) {
+		t.Fatal("eroded maps differ for the same seed")
+	}
+	if reflect.DeepEqual(a.elevation, base.elevation) {
+		t.Fatal("erosion didn't change the elevation")
+	}
+	if heightfield.Max(a.sediment) != 1 || heightfield.Max(base.sediment) != 0 {
+		t.Fatal("sediment map isn't scaled to [0, 1], or not eroded")
+	}
+	// the cells with the most sediment are wetter than their noise alone
+	p.SedimentMoisture = 0
+	dry := GenerateWorldMap(48, 48, seed, p)
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 48; x++ {
+			if a.sediment[y][x] > 0.5 && dry.moisture[y][x] < 1 &&
+				a.moisture[y][x] <= dry.moisture[y][x] {
+				t.Fatalf("sediment at %d, %d didn't add moisture", x, y)
+			}
+		}
+	}
+}
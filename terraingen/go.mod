@@ -5,6 +5,16 @@ go 1.19
 require (
 	github.com/aquilax/go-perlin v1.1.0
 	github.com/beefsack/go-astar v0.0.0-20200827232313-4ecf9e304482
-	github.com/disiqueira/gotree v1.0.0
+	github.com/dt-rush/gamedev-sketchbook/gridpath v0.0.0
+	github.com/dt-rush/gamedev-sketchbook/heightfield v0.0.0
+	github.com/dt-rush/gamedev-sketchbook/simclock v0.0.0
 	github.com/veandco/go-sdl2 v0.4.30
 )
+
+require github.com/disiqueira/gotree v1.0.0 // indirect
+
+replace github.com/dt-rush/gamedev-sketchbook/gridpath => ../gridpath
+
+replace github.com/dt-rush/gamedev-sketchbook/heightfield => ../heightfield
+
+replace github.com/dt-rush/gamedev-sketchbook/simclock => ../simclock
@@ -2,85 +2,139 @@ package main
 
 import (
 	"github.com/beefsack/go-astar"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"math"
 	"time"
 )
 
-func (w *World) ComputePath() float64 {
-	return w.ComputeEntityPathHandRolled()
+// search for the entity's path to its move target (with Theta* or HPA*,
+// smoothing it if asked), returning how long the search took and its
+// result. If there's no entity or it has nowhere to go, the result is empty
+func (w *World) ComputePath() (float64, gridpath.PathResult, error) {
+	var t_ms float64
+	var res gridpath.PathResult
+	var err error
+	if w.theta {
+		t_ms, res, err = w.ComputeEntityPathTheta()
+	} else {
+		t_ms, res, err = w.ComputeEntityPathHPA()
+	}
+	if w.smooth && w.e != nil && len(w.e.path) > 0 {
+		w.e.path = w.SmoothPath(w.e.path)
+	}
+	return t_ms, res, err
+}
+
+// set the entity walking along path, or stop it where it is if the search
+// failed
+func (w *World) setPath(path []Position, err error) {
+	if err != nil {
+		w.e.moveTarget = nil
+		w.e.path = nil
+		return
+	}
+	w.e.path = path
 }
 
-func (w *World) ComputeEntityPathHandRolled() float64 {
+func (w *World) ComputeEntityPathHPA() (
+	float64, gridpath.PathResult, error) {
 	var t_ms float64
+	var res gridpath.PathResult
+	var err error
 	if w.e != nil && w.e.moveTarget != nil {
 		t0 := time.Now()
-		path := w.c2.Path(
-			w.m.CellAt(w.e.pos).pos,
-			w.m.CellAt(*w.e.moveTarget).pos)
+		res, err = w.hpa.Find(w.e.pos, *w.e.moveTarget)
 		t_ms = float64(time.Since(t0).Nanoseconds()) / float64(1e6)
-		if len(path) > 0 {
-			w.e.path = path
-		}
+		w.setPath(res.Path, err)
 	}
-	w.c.Clear()
-	return t_ms
+	return t_ms, res, err
 }
 
-func (w *World) ComputeEntityPathUnrolled() float64 {
+// any-angle search, with the cells along each segment filled back in so
+// the entity still steps a cell at a time (res.Path holds just the
+// waypoints)
+func (w *World) ComputeEntityPathTheta() (
+	float64, gridpath.PathResult, error) {
 	var t_ms float64
+	var res gridpath.PathResult
+	var err error
 	if w.e != nil && w.e.moveTarget != nil {
 		t0 := time.Now()
-		path, _, found := w.c.Path(
-			w.m.CellAt(w.e.pos),
-			w.m.CellAt(*w.e.moveTarget))
+		res, err = w.pc.Find(w.e.pos, *w.e.moveTarget, gridpath.THETA)
 		t_ms = float64(time.Since(t0).Nanoseconds()) / float64(1e6)
-		if found {
-			w.e.path = path
-		}
+		w.setPath(gridpath.PathCells(res.Path), err)
 	}
-	w.c.Clear()
-	return t_ms
+	return t_ms, res, err
 }
 
-func (w *World) ComputeEntityPath() float64 {
+func (w *World) ComputeEntityPathHandRolled() (
+	float64, gridpath.PathResult, error) {
 	var t_ms float64
+	var res gridpath.PathResult
+	var err error
 	if w.e != nil && w.e.moveTarget != nil {
 		t0 := time.Now()
-		path, _, found := astar.Path(
-			w.m.CellAt(w.e.pos),
-			w.m.CellAt(*w.e.moveTarget))
+		res, err = w.pc.Find(w.e.pos, *w.e.moveTarget, gridpath.ASTAR)
 		t_ms = float64(time.Since(t0).Nanoseconds()) / float64(1e6)
-		if found {
-			cellsPath := make([]Position, len(path))
-			for i, pather := range path {
-				cellsPath[i] = pather.(*WorldMapCell).pos
-			}
-			w.e.path = cellsPath
-		}
+		w.setPath(res.Path, err)
 	}
-	return t_ms
+	return t_ms, res, err
 }
 
-func (w *World) MoveEntity() {
-	if w.e != nil && w.e.moveTarget != nil && w.e.path != nil && len(w.e.path) > 1 {
-		last_ix := len(w.e.path) - 1
-		var target Position
-		var validTarget = false
-		for !validTarget {
-			target = w.e.path[last_ix]
-			reached := (w.e.pos == target)
-			if reached {
-				w.e.path = w.e.path[:last_ix]
-				last_ix--
-			} else {
-				validTarget = true
-			}
-		}
-		w.e.pos = w.e.path[last_ix]
-		w.e.path = w.e.path[:last_ix]
-		if len(w.e.path) == 0 {
-			w.e.moveTarget = nil
-			w.e.path = nil
-		}
+// search with the go-astar library, adapting its answer to the shape of
+// the gridpath searches: the path runs from start to goal, and the cost is
+// its distance scaled by 10 (gridpath's units, though go-astar costs a
+// diagonal step sqrt(2) rather than 1.4). go-astar doesn't report how many
+// nodes it expanded, so Expanded is always 0
+func (w *World) ComputeEntityPath() (float64, gridpath.PathResult, error) {
+	var t_ms float64
+	var res gridpath.PathResult
+	var err error
+	if w.e != nil && w.e.moveTarget != nil {
+		t0 := time.Now()
+		res, err = w.goAstarFind(w.e.pos, *w.e.moveTarget)
+		t_ms = float64(time.Since(t0).Nanoseconds()) / float64(1e6)
+		w.setPath(res.Path, err)
 	}
+	return t_ms, res, err
+}
+
+func (w *World) goAstarFind(
+	start Position, end Position) (gridpath.PathResult, error) {
+	if err := gridpath.CheckEndpoints(w.m, start, end); err != nil {
+		return gridpath.PathResult{}, err
+	}
+	path, distance, found := astar.Path(w.m.CellAt(start), w.m.CellAt(end))
+	if !found {
+		return gridpath.PathResult{}, &gridpath.PathError{
+			Start: start, End: end, Err: gridpath.ErrUnreachable}
+	}
+	// go-astar lists the path goal first
+	cells := make([]Position, len(path))
+	for i, pather := range path {
+		cells[len(path)-1-i] = pather.(*WorldMapCell).pos
+	}
+	return gridpath.PathResult{
+		Path: cells,
+		Cost: int(math.Round(distance * 10)),
+	}, nil
+}
 
+// step the entity to the next cell of its path, dropping its move target
+// once the path is walked
+func (w *World) MoveEntity() {
+	if w.e == nil || w.e.moveTarget == nil {
+		return
+	}
+	for len(w.e.path) > 0 && w.e.path[0] == w.e.pos {
+		w.e.path = w.e.path[1:]
+	}
+	if len(w.e.path) > 0 {
+		w.e.pos = w.e.path[0]
+		w.e.path = w.e.path[1:]
+	}
+	if len(w.e.path) == 0 {
+		w.e.moveTarget = nil
+		w.e.path = nil
+	}
 }
@@ -3,6 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"github.com/dt-rush/gamedev-sketchbook/heightfield"
+	"github.com/dt-rush/gamedev-sketchbook/simclock"
 	"github.com/veandco/go-sdl2/sdl"
 	"log"
 	"math/rand"
@@ -13,9 +16,12 @@ import (
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "if provided, use as filename of prof output")
+var mapsize = flag.Int("size", WORLD_CELLDIMENSION, "width and height of the map in cells")
+var biomes = flag.String("biomes", "", "if provided, load the biome table from this JSON file (see biomes.json)")
+var erode = flag.Float64("erode", 0, "if provided, erode the maps with this many droplets per cell")
+var seed = flag.Int64("seed", 0, "if provided, generate the first map from this seed (as printed at startup)")
 
 func init() {
-	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 }
 
@@ -33,14 +39,34 @@ func handleQuit(e sdl.Event) bool {
 	return true
 }
 
+// print how a path search went
+func reportPath(ms float64, res gridpath.PathResult, err error) {
+	fmt.Printf("path calculation took %.3f ms\n", ms)
+	if err != nil {
+		fmt.Printf("no path: %v\n", err)
+	} else if len(res.Path) > 0 {
+		fmt.Printf("path length %d, cost %d, %d nodes expanded\n",
+			len(res.Path), res.Cost, res.Expanded)
+	}
+}
+
 func handleKeyEvents(w *World, e sdl.Event) {
 	switch e.(type) {
 	case *sdl.KeyboardEvent:
 		ke := e.(*sdl.KeyboardEvent)
 		if ke.Keysym.Sym == sdl.K_g && ke.Type == sdl.KEYDOWN {
 			w.RegenMap()
-			ms := w.ComputePath()
-			fmt.Printf("path calculation took %.3f ms\n", ms)
+			reportPath(w.ComputePath())
+		}
+		if ke.Keysym.Sym == sdl.K_t && ke.Type == sdl.KEYDOWN {
+			w.theta = !w.theta
+			fmt.Printf("theta* search: %v\n", w.theta)
+			reportPath(w.ComputePath())
+		}
+		if ke.Keysym.Sym == sdl.K_s && ke.Type == sdl.KEYDOWN {
+			w.smooth = !w.smooth
+			fmt.Printf("path smoothing: %v\n", w.smooth)
+			reportPath(w.ComputePath())
 		}
 	}
 }
@@ -50,9 +76,13 @@ func handleMouseEvents(w *World, e sdl.Event) {
 	case *sdl.MouseButtonEvent:
 		me := e.(*sdl.MouseButtonEvent)
 		if me.Type == sdl.MOUSEBUTTONDOWN {
+			cw, ch := w.m.CellPixelSize()
 			pos := Position{
-				int(float64(me.X) / WORLD_CELL_PIXEL_WIDTH),
-				int(float64(WINDOW_HEIGHT-me.Y) / WORLD_CELL_PIXEL_HEIGHT)}
+				X: int(float64(me.X) / cw),
+				Y: int(float64(WINDOW_HEIGHT-me.Y) / ch)}
+			if !w.m.InGrid(pos.X, pos.Y) {
+				return
+			}
 			if me.Button == sdl.BUTTON_LEFT {
 				e := Entity{pos: pos}
 				w.e = &e
@@ -60,17 +90,41 @@ func handleMouseEvents(w *World, e sdl.Event) {
 			if me.Button == sdl.BUTTON_RIGHT {
 				if w.e != nil {
 					w.e.moveTarget = &pos
-					ms := w.ComputePath()
-					fmt.Printf("path calculation took %.3f ms\n", ms)
+					reportPath(w.ComputePath())
 				}
 			}
+			if me.Button == sdl.BUTTON_MIDDLE {
+				// cycle the kind of the clicked cell
+				kind := (w.m.CellAt(pos).kind + 1) % len(w.m.params.Biomes.Biomes)
+				t0 := time.Now()
+				w.SetCellKind(pos, kind)
+				fmt.Printf("sector rebuild took %.3f ms\n",
+					float64(time.Since(t0).Nanoseconds())/float64(1e6))
+			}
 		}
 	}
 }
 
 func gameloop() int {
 
-	w := NewWorld()
+	mapSeed := *seed
+	if mapSeed == 0 {
+		mapSeed = RandomSeed()
+	}
+	params := DEFAULT_GEN_PARAMS
+	if *biomes != "" {
+		t, err := LoadBiomes(*biomes)
+		if err != nil {
+			log.Fatal("could not load biomes: ", err)
+		}
+		params.Biomes = t
+	}
+	if *erode > 0 {
+		ep := heightfield.DEFAULT_EROSION
+		ep.Hydraulic.Droplets = int(*erode * float64(*mapsize**mapsize))
+		params.Erosion = &ep
+	}
+	w := NewSeededWorld(*mapsize, *mapsize, mapSeed, params)
 
 	sdl.Init(sdl.INIT_EVERYTHING)
 	r, exitcode := GetRenderer()
@@ -78,7 +132,19 @@ func gameloop() int {
 		return exitcode
 	}
 
-	moveTicker := time.NewTicker(50 * time.Millisecond)
+	clock := simclock.New(SIM_DT, simclock.RealTime{})
+	clock.EveryDuration(MOVE_PERIOD, func(int) {
+		w.MoveEntity()
+	})
+	clock.EveryDuration(time.Second/FPS, func(int) {
+		r.SetDrawColor(0, 0, 0, 255)
+		r.Clear()
+
+		w.DrawWorldMap(r)
+		w.DrawEntityAndPath(r)
+
+		r.Present()
+	})
 
 	running := true
 	for running {
@@ -87,26 +153,20 @@ func gameloop() int {
 			handleKeyEvents(w, e)
 			handleMouseEvents(w, e)
 		}
-		r.SetDrawColor(0, 0, 0, 255)
-		r.Clear()
 
-		w.DrawWorldMap(r)
-		w.DrawEntityAndPath(r)
+		clock.Update()
 
-		select {
-		case _ = <-moveTicker.C:
-			w.MoveEntity()
-		default:
+		if wait := clock.UntilNext(); wait > 0 {
+			sdl.Delay(uint32(wait / time.Millisecond))
 		}
-
-		r.Present()
-		sdl.Delay(1000 / FPS)
 	}
 	fmt.Println("Done")
 	return 0
 }
 
 func main() {
+	// parsed here rather than in init() so that `go test` flags don't trip it
+	flag.Parse()
 
 	var exitcode int
 	sdl.Main(func() {
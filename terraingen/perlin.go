@@ -25,9 +25,20 @@ func OpPerlins(a [][]float64, b [][]float64,
 	sum := make([][]float64, len(a))
 	for y := 0; y < len(a); y++ {
 		sum[y] = make([]float64, len(a[y]))
-		for x := 0; x < len(a); x++ {
+		for x := 0; x < len(a[y]); x++ {
 			sum[y][x] = op(a[y][x], b[y][x])
 		}
 	}
 	return sum
 }
+
+func MapPerlin(a [][]float64, op func(a float64) float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for y := 0; y < len(a); y++ {
+		out[y] = make([]float64, len(a[y]))
+		for x := 0; x < len(a[y]); x++ {
+			out[y][x] = op(a[y][x])
+		}
+	}
+	return out
+}
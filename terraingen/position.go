@@ -1,17 +1,10 @@
 package main
 
 import (
-	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 )
 
-type Position struct {
-	X int
-	Y int
-}
-
-func (p Position) String() string {
-	return fmt.Sprintf("[%d, %d]", p.X, p.Y)
-}
+type Position = gridpath.Position
 
 type PositionPair struct {
 	p1 Position
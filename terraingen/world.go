@@ -2,24 +2,57 @@ package main
 
 import (
 	"fmt"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
 )
 
 type World struct {
-	m  *WorldMap
-	e  *Entity
-	c  *PathCalculator
-	c2 *PathComputer
+	m   *WorldMap
+	e   *Entity
+	pc  *gridpath.PathComputer
+	hpa *gridpath.HPA
+	// whether paths are string-pulled into straight runs of cells
+	smooth bool
+	// whether paths are searched with Theta* instead of HPA*
+	theta bool
 }
 
-func NewWorld() *World {
-	w := World{}
-	w.RegenMap()
-	w.c = NewPathCalculator(w.m)
-	w.c2 = NewPathComputer(w.m)
-	return &w
+// a world on a freshly-seeded map
+func NewWorld(w int, h int) *World {
+	return NewSeededWorld(w, h, RandomSeed(), DEFAULT_GEN_PARAMS)
+}
+
+// a world on the map generated from seed and p
+func NewSeededWorld(w int, h int, seed int64, p GenParams) *World {
+	wo := World{}
+	wo.m = GenerateWorldMap(w, h, seed, p)
+	fmt.Printf("seed: %d (rerun with -seed %d for this map)\n",
+		wo.m.seed, wo.m.seed)
+	wo.pc = gridpath.NewPathComputer(wo.m, w, h)
+	wo.pc.Heuristic = gridpath.OctileHeuristic
+	wo.hpa = gridpath.NewHPA(wo.m, w, h, HPA_CLUSTERSIZE)
+	wo.hpa.Heuristic = gridpath.OctileHeuristic
+	return &wo
 }
 
 func (w *World) RegenMap() {
-	w.m = GenerateWorldMap()
-	fmt.Printf("seed: %d\n", w.m.seed)
+	w.m = GenerateWorldMap(w.m.w, w.m.h, RandomSeed(), w.m.params)
+	fmt.Printf("seed: %d (rerun with -seed %d for this map)\n",
+		w.m.seed, w.m.seed)
+	w.pc.Grid = w.m
+	w.hpa.Grid = w.m
+	w.hpa.Build()
+}
+
+// SmoothPath string-pulls path (only where the straight line is no more
+// costly than the cells it replaces) and fills the cells back in along the
+// straight segments, so the entity still steps a cell at a time
+func (w *World) SmoothPath(path []Position) []Position {
+	return gridpath.PathCells(gridpath.StringPull(w.m, path))
+}
+
+// SetCellKind changes a single cell of the map, rebuilding only the HPA*
+// sector it lies in
+func (w *World) SetCellKind(pos Position, kind int) {
+	w.m.SetCell(pos, w.m.KindCell(kind))
+	w.hpa.UpdateCell(pos.X, pos.Y)
 }
@@ -1,73 +1,89 @@
 package main
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
-	"time"
+	"github.com/dt-rush/gamedev-sketchbook/gridpath"
+	"io"
+	"math"
 )
 
 type WorldMap struct {
-	seed  int64
-	cells [WORLD_CELLHEIGHT][WORLD_CELLWIDTH]WorldMapCell
-}
-
-func GenerateWorldMap() *WorldMap {
-	m := WorldMap{}
-	m.seed = time.Now().UnixNano()
-	// m.seed = 1529124576499821233 // nice seed
-	// m.seed = 1529127452575316215
-	terrain := PerlinNoiseInt2D(
-		WORLD_CELLWIDTH, WORLD_CELLHEIGHT, 16,
-		2.0, 2.0, 3,
-		m.seed)
-	water := PerlinNoiseInt2D(
-		WORLD_CELLWIDTH, WORLD_CELLHEIGHT, 32,
-		4.0, 2.0, 3,
-		m.seed)
-	world := OpPerlins(terrain, water, func(a float64, b float64) float64 {
-		x := (a + (a + 0.3) - b) / 2
-		if x < 0 {
-			return 0
-		} else if x > 1 {
-			return 1
-		} else {
-			return x
-		}
-	})
-	for y := 0; y < WORLD_CELLHEIGHT; y++ {
-		for x := 0; x < WORLD_CELLWIDTH; x++ {
-			var v = world[y][x]
-			var c WorldMapCell
-			if v < 0.4 {
-				depth := int(v / 0.1)
-				c = m.WaterCell(depth)
-			} else if v < 0.45 {
-				c = m.SandCell()
-			} else if v < 0.55 {
-				c = m.GrassCell()
-			} else {
-				density := v
-				c = m.ForestCell(density)
-			}
-			c.pos = Position{x, y}
-			m.cells[y][x] = c
-		}
-	}
-	return &m
+	seed   int64
+	params GenParams
+	w      int
+	h      int
+	cells  [][]WorldMapCell
+	// the layers the cells' biomes were classified from, indexed [y][x]
+	// like cells, all in [0, 1]
+	elevation   [][]float64
+	moisture    [][]float64
+	temperature [][]float64
+	// where erosion left sediment, scaled to [0, 1] (all 0 if the map
+	// wasn't eroded)
+	sediment [][]float64
 }
 
 func (m *WorldMap) CellAt(pos Position) *WorldMapCell {
 	return &m.cells[pos.Y][pos.X]
 }
 
+// SetCell replaces the cell at pos (callers holding a pathfinding
+// abstraction of the map need to update it, see World.SetCellKind)
+func (m *WorldMap) SetCell(pos Position, c WorldMapCell) {
+	c.pos = pos
+	m.cells[pos.Y][pos.X] = c
+}
+
+// size in pixels of a cell when the map fills the window
+func (m *WorldMap) CellPixelSize() (w float64, h float64) {
+	return float64(WINDOW_WIDTH) / float64(m.w),
+		float64(WINDOW_HEIGHT) / float64(m.h)
+}
+
 func (m *WorldMap) InGrid(x int, y int) bool {
-	return x >= 0 && x < WORLD_CELLWIDTH && y >= 0 && y < WORLD_CELLHEIGHT
+	return x >= 0 && x < m.w && y >= 0 && y < m.h
+}
+
+func (m *WorldMap) Passable(x int, y int) bool {
+	return true
+}
+
+func (m *WorldMap) Cost(x int, y int) int {
+	return m.params.Biomes.Biomes[m.cells[y][x].kind].Cost
+}
+
+func (m *WorldMap) Neighbors(p Position, buf []Position) []Position {
+	return gridpath.AppendNeighbors(m, p, buf, true)
 }
 
 func (m *WorldMap) Print() {
-	for y := 0; y < WORLD_CELLHEIGHT; y++ {
-		for x := 0; x < WORLD_CELLWIDTH; x++ {
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
 			fmt.Printf("%s", m.cells[y][x].rep)
 		}
 		fmt.Println()
 	}
 }
+
+// Encode writes the map as bytes: its seed and size, then each cell's kind
+// and colour (and its density, if its biome has one), row by row. Two maps
+// are the same if they encode the same
+func (m *WorldMap) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	binary.Write(bw, binary.LittleEndian,
+		[]int64{m.seed, int64(m.w), int64(m.h)})
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			c := &m.cells[y][x]
+			bw.Write([]byte{byte(c.kind), c.color.R, c.color.G, c.color.B})
+			if d, ok := c.data.(CellData); ok &&
+				m.params.Biomes.Biomes[c.kind].Density {
+				binary.Write(bw, binary.LittleEndian,
+					math.Float64bits(d.Density))
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -4,20 +4,6 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-const (
-	CELL_WATER  = iota
-	CELL_SAND   = iota
-	CELL_GRASS  = iota
-	CELL_FOREST = iota
-)
-
-var TERRAIN_COSTS = []int{
-	100,
-	1,
-	1,
-	40,
-}
-
 type WorldMapCell struct {
 	m     *WorldMap
 	rep   string
@@ -27,6 +13,15 @@ type WorldMapCell struct {
 	data  interface{}
 }
 
+// what a cell carries besides its biome (as WorldMapCell.data)
+//
+// Density:		the height the cell was generated at, if its biome has Density set
+// Props:		the biome's Data
+type CellData struct {
+	Density float64
+	Props   map[string]float64
+}
+
 func NewWorldMapCell(m *WorldMap, rep string, kind int, color sdl.Color) WorldMapCell {
 	return WorldMapCell{
 		m:     m,
@@ -35,28 +30,23 @@ func NewWorldMapCell(m *WorldMap, rep string, kind int, color sdl.Color) WorldMa
 		color: color}
 }
 
-func (m *WorldMap) WaterCell(depth int) WorldMapCell {
-	return NewWorldMapCell(m, "o", CELL_WATER,
-		sdl.Color{R: 0, G: 0, B: uint8(48 + 16*depth)})
-}
-
-func (m *WorldMap) SandCell() WorldMapCell {
-	return NewWorldMapCell(m, ".", CELL_SAND,
-		sdl.Color{R: 182, G: 182, B: 0})
-}
-
-func (m *WorldMap) GrassCell() WorldMapCell {
-	return NewWorldMapCell(m, ".", CELL_GRASS,
-		sdl.Color{R: 0, G: 182, B: 0})
-}
-
-type ForestCellData struct {
-	density float64
+// BiomeCell makes a cell of the biome of the given kind, as generated at
+// height v
+func (m *WorldMap) BiomeCell(kind int, v float64) WorldMapCell {
+	b := &m.params.Biomes.Biomes[kind]
+	c := NewWorldMapCell(m, b.Glyph, kind, b.ColorAt(v))
+	if b.Density || b.Data != nil {
+		d := CellData{Props: b.Data}
+		if b.Density {
+			d.Density = v
+		}
+		c.data = d
+	}
+	return c
 }
 
-func (m *WorldMap) ForestCell(density float64) WorldMapCell {
-	c := NewWorldMapCell(m, "#", CELL_FOREST,
-		sdl.Color{R: 0, G: uint8(180 - (density/0.1)*16), B: 0})
-	c.data = ForestCellData{density}
-	return c
+// KindCell makes a typical cell of the given kind (for editing the map by
+// hand)
+func (m *WorldMap) KindCell(kind int) WorldMapCell {
+	return m.BiomeCell(kind, m.params.Biomes.TypicalHeight(kind))
 }